@@ -0,0 +1,127 @@
+// Package approx 提供统计海量label场景下使用的近似数据结构：
+// Count-Min Sketch（近似计数）、Misra-Gries重hitter摘要（近似Top-K）
+// 以及HyperLogLog（近似基数），用于替代`map[string]int64`在超大label空间下的内存占用。
+package approx
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// cmsSeedBase 是生成每一行哈希系数的基础种子，固定取值以保证不同worker
+// 各自创建的CMS使用完全相同的哈希函数，从而可以直接做elementwise合并。
+const cmsSeedBase uint64 = 0x9E3779B97F4A7C15
+
+// CMS 是一个Count-Min Sketch，width=ceil(e/epsilon)，depth=ceil(ln(1/delta))
+type CMS struct {
+	width   int
+	depth   int
+	rows    [][]uint64
+	coeffs  [][4]uint64 // 每一行4个系数，构成度为3的多项式哈希（在mod 2^64上计算）
+	epsilon float64
+	delta   float64
+}
+
+// NewCMS 按误差参数构造CMS，epsilon控制单次估计的误差幅度(±epsilon*N)，
+// delta控制该误差界限被突破的概率上限。
+func NewCMS(epsilon, delta float64) *CMS {
+	width := int(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	if width < 1 {
+		width = 1
+	}
+	if depth < 1 {
+		depth = 1
+	}
+
+	c := &CMS{
+		width:   width,
+		depth:   depth,
+		epsilon: epsilon,
+		delta:   delta,
+	}
+
+	c.rows = make([][]uint64, depth)
+	c.coeffs = make([][4]uint64, depth)
+	seed := cmsSeedBase
+	for i := 0; i < depth; i++ {
+		c.rows[i] = make([]uint64, width)
+		for j := 0; j < 4; j++ {
+			seed = splitmix64(seed)
+			c.coeffs[i][j] = seed | 1 // 保证为奇数，减少退化碰撞
+		}
+	}
+
+	return c
+}
+
+// splitmix64 是一个简单的确定性伪随机数生成器，用于在不依赖外部随机源的
+// 情况下，为每个worker独立生成完全一致的哈希系数序列。
+func splitmix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	z := x
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// hashKey 将字符串key映射为64位整数，作为多项式哈希的输入
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// rowHash 对第row行使用该行专属的4个系数计算度为3的多项式哈希，再对width取模
+// 得到桶下标。多项式在mod 2^64的环上计算而不是在素数域上，因此不具备4-wise
+// 独立性：结果的低位只取决于x的低位，低位相同的输入在低位上总是相同。
+func (c *CMS) rowHash(row int, x uint64) int {
+	a := c.coeffs[row]
+	// Horner法则求 a0 + a1*x + a2*x^2 + a3*x^3 (mod 2^64)，溢出回绕等价于取模2^64
+	v := a[3]
+	v = v*x + a[2]
+	v = v*x + a[1]
+	v = v*x + a[0]
+	return int(v % uint64(c.width))
+}
+
+// Add 为key增加count计数
+func (c *CMS) Add(key string, count int64) {
+	x := hashKey(key)
+	for i := 0; i < c.depth; i++ {
+		j := c.rowHash(i, x)
+		c.rows[i][j] += uint64(count)
+	}
+}
+
+// Estimate 返回key的近似计数，真实值不会超过该估计值，误差上界为 epsilon*N
+func (c *CMS) Estimate(key string) int64 {
+	x := hashKey(key)
+	var min uint64 = math.MaxUint64
+	for i := 0; i < c.depth; i++ {
+		j := c.rowHash(i, x)
+		if c.rows[i][j] < min {
+			min = c.rows[i][j]
+		}
+	}
+	return int64(min)
+}
+
+// ErrorBound 返回在总事件数为n的情况下，该CMS的绝对误差上界 epsilon*n
+func (c *CMS) ErrorBound(n int64) int64 {
+	return int64(math.Ceil(c.epsilon * float64(n)))
+}
+
+// Merge 将other的计数累加到c上，要求两者width/depth一致（即由相同的epsilon/delta构造）
+func (c *CMS) Merge(other *CMS) error {
+	if c.width != other.width || c.depth != other.depth {
+		return fmt.Errorf("approx: CMS维度不一致，无法合并 (%dx%d vs %dx%d)", c.depth, c.width, other.depth, other.width)
+	}
+	for i := 0; i < c.depth; i++ {
+		for j := 0; j < c.width; j++ {
+			c.rows[i][j] += other.rows[i][j]
+		}
+	}
+	return nil
+}
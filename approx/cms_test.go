@@ -0,0 +1,57 @@
+package approx
+
+import "testing"
+
+func TestCMSAddAndEstimate(t *testing.T) {
+	c := NewCMS(0.01, 0.01)
+
+	c.Add("cat", 5)
+	c.Add("dog", 3)
+	c.Add("cat", 2)
+
+	if got := c.Estimate("cat"); got < 7 {
+		t.Errorf("Estimate(cat) = %d, want >= 7 (CMS never underestimates)", got)
+	}
+	if got := c.Estimate("dog"); got < 3 {
+		t.Errorf("Estimate(dog) = %d, want >= 3", got)
+	}
+	if got := c.Estimate("bird"); got != 0 {
+		t.Errorf("Estimate(bird) = %d, want 0 for a key never added", got)
+	}
+}
+
+func TestCMSErrorBound(t *testing.T) {
+	c := NewCMS(0.1, 0.1)
+	if got := c.ErrorBound(1000); got != 100 {
+		t.Errorf("ErrorBound(1000) = %d, want 100 for epsilon=0.1", got)
+	}
+}
+
+func TestCMSMergeSumsCounts(t *testing.T) {
+	a := NewCMS(0.01, 0.01)
+	b := NewCMS(0.01, 0.01)
+
+	a.Add("cat", 5)
+	b.Add("cat", 3)
+	b.Add("dog", 2)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+
+	if got := a.Estimate("cat"); got < 8 {
+		t.Errorf("Estimate(cat) after merge = %d, want >= 8", got)
+	}
+	if got := a.Estimate("dog"); got < 2 {
+		t.Errorf("Estimate(dog) after merge = %d, want >= 2", got)
+	}
+}
+
+func TestCMSMergeRejectsMismatchedDimensions(t *testing.T) {
+	a := NewCMS(0.01, 0.01)
+	b := NewCMS(0.1, 0.1)
+
+	if err := a.Merge(b); err == nil {
+		t.Fatalf("Merge() error = nil, want an error for mismatched width/depth")
+	}
+}
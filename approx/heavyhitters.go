@@ -0,0 +1,100 @@
+package approx
+
+import "sort"
+
+// HeavyHitters 是一个Misra-Gries重hitter摘要，用固定大小k的计数器集合
+// 近似找出频次最高的若干个key，内存占用与k而非label总数成正比。
+type HeavyHitters struct {
+	k        int
+	counters map[string]int64
+}
+
+// NewHeavyHitters 创建容量为k的摘要
+func NewHeavyHitters(k int) *HeavyHitters {
+	return &HeavyHitters{
+		k:        k,
+		counters: make(map[string]int64, k),
+	}
+}
+
+// Add 处理一次key出现：若key已在计数器中则自增；否则若还有空位则新增计数器；
+// 否则对所有计数器减一，归零的计数器被移除（Misra-Gries核心步骤）。
+func (h *HeavyHitters) Add(key string) {
+	if _, ok := h.counters[key]; ok {
+		h.counters[key]++
+		return
+	}
+
+	if len(h.counters) < h.k {
+		h.counters[key] = 1
+		return
+	}
+
+	for k, c := range h.counters {
+		if c <= 1 {
+			delete(h.counters, k)
+		} else {
+			h.counters[k] = c - 1
+		}
+	}
+}
+
+// Merge 将other的计数器合并进h：重叠的key求和，随后若摘要超出容量k，
+// 按Misra-Gries的方式对所有计数器减去第(k+1)大的值，减到不大于0的计数器被移除；
+// 合并后不超过k个key时不做任何递减。
+func (h *HeavyHitters) Merge(other *HeavyHitters) {
+	for k, c := range other.counters {
+		h.counters[k] += c
+	}
+
+	if len(h.counters) <= h.k {
+		return
+	}
+
+	values := make([]int64, 0, len(h.counters))
+	for _, c := range h.counters {
+		values = append(values, c)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] > values[j] })
+
+	threshold := values[h.k] // 第(k+1)大的值（0-indexed下标为k）
+
+	for key, c := range h.counters {
+		remaining := c - threshold
+		if remaining <= 0 {
+			delete(h.counters, key)
+		} else {
+			h.counters[key] = remaining
+		}
+	}
+}
+
+// TopN 返回摘要中计数最高的n个key及其近似计数，按计数降序排列
+func (h *HeavyHitters) TopN(n int) []struct {
+	Key   string
+	Count int64
+} {
+	items := make([]struct {
+		Key   string
+		Count int64
+	}, 0, len(h.counters))
+
+	for k, c := range h.counters {
+		items = append(items, struct {
+			Key   string
+			Count int64
+		}{Key: k, Count: c})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count == items[j].Count {
+			return items[i].Key < items[j].Key
+		}
+		return items[i].Count > items[j].Count
+	})
+
+	if n < len(items) {
+		items = items[:n]
+	}
+	return items
+}
@@ -0,0 +1,77 @@
+package approx
+
+import "testing"
+
+func TestHeavyHittersAddWithinCapacity(t *testing.T) {
+	h := NewHeavyHitters(10)
+
+	h.Add("cat")
+	h.Add("cat")
+	h.Add("dog")
+
+	top := h.TopN(2)
+	if len(top) != 2 {
+		t.Fatalf("TopN(2) returned %d items, want 2", len(top))
+	}
+	if top[0].Key != "cat" || top[0].Count != 2 {
+		t.Errorf("top[0] = %+v, want {cat 2}", top[0])
+	}
+	if top[1].Key != "dog" || top[1].Count != 1 {
+		t.Errorf("top[1] = %+v, want {dog 1}", top[1])
+	}
+}
+
+func TestHeavyHittersEvictsWhenOverCapacity(t *testing.T) {
+	h := NewHeavyHitters(2)
+
+	h.Add("cat")
+	h.Add("cat")
+	h.Add("cat")
+	h.Add("dog")
+	h.Add("bird") // 超过容量k=2，触发Misra-Gries的全体递减
+
+	if len(h.counters) > 2 {
+		t.Fatalf("len(counters) = %d, want <= k=2 after eviction", len(h.counters))
+	}
+
+	top := h.TopN(1)
+	if len(top) != 1 || top[0].Key != "cat" {
+		t.Fatalf("TopN(1) = %+v, want the heaviest hitter cat to survive eviction", top)
+	}
+}
+
+func TestHeavyHittersMergeSumsOverlappingKeys(t *testing.T) {
+	a := NewHeavyHitters(10)
+	b := NewHeavyHitters(10)
+
+	a.Add("cat")
+	a.Add("cat")
+	b.Add("cat")
+	b.Add("dog")
+
+	a.Merge(b)
+
+	top := a.TopN(10)
+	counts := map[string]int64{}
+	for _, it := range top {
+		counts[it.Key] = it.Count
+	}
+	if counts["cat"] != 3 {
+		t.Errorf("counts[cat] = %d, want 3", counts["cat"])
+	}
+	if counts["dog"] != 1 {
+		t.Errorf("counts[dog] = %d, want 1", counts["dog"])
+	}
+}
+
+func TestHeavyHittersTopNLimitsResults(t *testing.T) {
+	h := NewHeavyHitters(10)
+	h.Add("cat")
+	h.Add("dog")
+	h.Add("bird")
+
+	top := h.TopN(1)
+	if len(top) != 1 {
+		t.Fatalf("TopN(1) returned %d items, want 1", len(top))
+	}
+}
@@ -0,0 +1,82 @@
+package approx
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHLLEstimateApproximatesDistinctCount(t *testing.T) {
+	h := NewHLL(DefaultHLLPrecision)
+
+	const distinct = 5000
+	keys := distinctTestKeys(distinct)
+	for _, k := range keys {
+		h.Add(k)
+	}
+	// 重复添加不应改变基数估计
+	for _, k := range keys {
+		h.Add(k)
+	}
+
+	got := h.Estimate()
+	// 标准误差约0.8%，放宽到5%以避免测试偶发抖动
+	low, high := uint64(distinct*0.95), uint64(distinct*1.05)
+	if got < low || got > high {
+		t.Errorf("Estimate() = %d, want within [%d, %d] of true cardinality %d", got, low, high, distinct)
+	}
+}
+
+func TestHLLMergeUnionsCardinality(t *testing.T) {
+	a := NewHLL(DefaultHLLPrecision)
+	b := NewHLL(DefaultHLLPrecision)
+
+	keys := distinctTestKeys(1500)
+	for _, k := range keys[:1000] {
+		a.Add(k)
+	}
+	for _, k := range keys[500:1500] {
+		b.Add(k)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+
+	got := a.Estimate()
+	low, high := uint64(1500*0.9), uint64(1500*1.1)
+	if got < low || got > high {
+		t.Errorf("Estimate() after merge = %d, want within [%d, %d] of union cardinality 1500", got, low, high)
+	}
+}
+
+func TestHLLMergeRejectsMismatchedPrecision(t *testing.T) {
+	a := NewHLL(10)
+	b := NewHLL(12)
+
+	if err := a.Merge(b); err == nil {
+		t.Fatalf("Merge() error = nil, want an error for mismatched precision")
+	}
+}
+
+// distinctTestKeys生成n个各不相同的随机label，避免使用共享长前缀的顺序key
+// ——fnv哈希对这类输入的高位比特分布较差，会让HLL的桶号明显扎堆，
+// 放大基数估计的误差，不代表真实label分布下的表现。
+func distinctTestKeys(n int) []string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	r := rand.New(rand.NewSource(42))
+	seen := make(map[string]bool, n)
+	keys := make([]string, 0, n)
+	for len(keys) < n {
+		b := make([]byte, 12)
+		for j := range b {
+			b[j] = letters[r.Intn(len(letters))]
+		}
+		k := string(b)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,43 @@
+package approx
+
+// DefaultHeavyHittersK 是Misra-Gries摘要的默认容量，足以覆盖绝大多数场景下
+// 需要关注的Top-K候选label，同时将内存占用固定在一个很小的常数。
+const DefaultHeavyHittersK = 1000
+
+// DefaultHLLPrecision 对应m=16384个寄存器，标准误差约0.8%
+const DefaultHLLPrecision = 14
+
+// Sketches 捆绑了一次扫描所需的全部近似数据结构：CMS用于估计任意label的计数，
+// HeavyHitters用于给出Top-K候选，HLL用于估计不同label的总数（基数）。
+// 每个worker持有独立的Sketches，扫描结束后通过Merge合并到全局结果。
+type Sketches struct {
+	CMS          *CMS
+	HeavyHitters *HeavyHitters
+	HLL          *HLL
+}
+
+// NewSketches 按误差参数构造一组Sketches，epsilon/delta控制CMS的精度，
+// hhK控制Top-K候选摘要的容量，hllPrecision控制基数估计的寄存器数量。
+func NewSketches(epsilon, delta float64, hhK int, hllPrecision uint) *Sketches {
+	return &Sketches{
+		CMS:          NewCMS(epsilon, delta),
+		HeavyHitters: NewHeavyHitters(hhK),
+		HLL:          NewHLL(hllPrecision),
+	}
+}
+
+// Add 记录一次label出现，同时更新三个数据结构
+func (s *Sketches) Add(label string) {
+	s.CMS.Add(label, 1)
+	s.HeavyHitters.Add(label)
+	s.HLL.Add(label)
+}
+
+// Merge 将other的统计结果合并进s
+func (s *Sketches) Merge(other *Sketches) error {
+	if err := s.CMS.Merge(other.CMS); err != nil {
+		return err
+	}
+	s.HeavyHitters.Merge(other.HeavyHitters)
+	return s.HLL.Merge(other.HLL)
+}
@@ -0,0 +1,53 @@
+package approx
+
+import "testing"
+
+func TestSketchesAddUpdatesAllThree(t *testing.T) {
+	s := NewSketches(0.01, 0.01, DefaultHeavyHittersK, DefaultHLLPrecision)
+
+	s.Add("cat")
+	s.Add("cat")
+	s.Add("dog")
+
+	if got := s.CMS.Estimate("cat"); got < 2 {
+		t.Errorf("CMS.Estimate(cat) = %d, want >= 2", got)
+	}
+
+	top := s.HeavyHitters.TopN(1)
+	if len(top) != 1 || top[0].Key != "cat" || top[0].Count != 2 {
+		t.Errorf("HeavyHitters.TopN(1) = %+v, want {cat 2}", top)
+	}
+
+	if got := s.HLL.Estimate(); got < 2 || got > 3 {
+		t.Errorf("HLL.Estimate() = %d, want ~2 distinct labels", got)
+	}
+}
+
+func TestSketchesMergeCombinesAllThree(t *testing.T) {
+	a := NewSketches(0.01, 0.01, DefaultHeavyHittersK, DefaultHLLPrecision)
+	b := NewSketches(0.01, 0.01, DefaultHeavyHittersK, DefaultHLLPrecision)
+
+	a.Add("cat")
+	b.Add("cat")
+	b.Add("dog")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+
+	if got := a.CMS.Estimate("cat"); got < 2 {
+		t.Errorf("CMS.Estimate(cat) after merge = %d, want >= 2", got)
+	}
+	if got := a.HLL.Estimate(); got < 2 || got > 3 {
+		t.Errorf("HLL.Estimate() after merge = %d, want ~2 distinct labels", got)
+	}
+}
+
+func TestSketchesMergePropagatesCMSError(t *testing.T) {
+	a := NewSketches(0.01, 0.01, DefaultHeavyHittersK, DefaultHLLPrecision)
+	b := NewSketches(0.1, 0.1, DefaultHeavyHittersK, DefaultHLLPrecision)
+
+	if err := a.Merge(b); err == nil {
+		t.Fatalf("Merge() error = nil, want an error from the mismatched CMS dimensions")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+/*
+# @Desc: 部分真实语料是GBK/Big5编码的，而不是严格UTF-8。wrapCharsetReader在
+# bufio.Scanner/jsonl.Scanner之前插入一层解码，把源编码转成UTF-8，
+# 避免因无效的UTF-8字节导致json.Unmarshal整行被跳过。
+*/
+
+// charsetByName 支持的charset名称（大小写不敏感）映射到对应的encoding.Encoding
+func charsetByName(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return nil, nil // nil表示无需转换
+	case "gbk":
+		return simplifiedchinese.GBK, nil
+	case "gb18030":
+		return simplifiedchinese.GB18030, nil
+	case "gb2312":
+		return simplifiedchinese.GBK, nil // GBK是GB2312(EUC-CN)的超集
+	case "big5":
+		return traditionalchinese.Big5, nil
+	case "utf-16le", "utf16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case "utf-16be", "utf16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	default:
+		return nil, fmt.Errorf("不支持的charset: %s", name)
+	}
+}
+
+// detectBOM 嗅探data开头的BOM字节，返回识别出的charset名称及BOM长度；
+// 未识别到任何已知BOM时返回("utf-8", 0)，即按UTF-8无BOM处理。
+func detectBOM(data []byte) (string, int) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF:
+		return "utf-8", 3
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return "utf-16le", 2
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return "utf-16be", 2
+	default:
+		return "utf-8", 0
+	}
+}
+
+// wrapCharsetReader 按charset参数包装r：
+//   - charset为"auto"时先嗅探BOM决定实际编码(无BOM则视为utf-8)
+//   - charset为具体编码名时直接使用该编码
+//
+// 返回包装后的io.Reader，以及最终判定/使用的编码名称，供调用方记录到metadata中。
+//
+// 注意："auto"只能识别出带BOM的文件，而GBK/Big5这类中文编码的真实语料几乎
+// 从不带BOM，会被auto误判为utf-8直接跳过解码，解析结果是乱码而不是报错。
+// auto不适合这类数据，调用方应显式传入-charset gbk/big5等。
+func wrapCharsetReader(r io.Reader, charset string) (io.Reader, string, error) {
+	if strings.EqualFold(charset, "auto") {
+		br := bufio.NewReaderSize(r, 4096)
+		peeked, _ := br.Peek(3)
+		detected, bomLen := detectBOM(peeked)
+		if bomLen > 0 {
+			if _, err := br.Discard(bomLen); err != nil {
+				return nil, detected, fmt.Errorf("跳过BOM失败: %v", err)
+			}
+		}
+
+		enc, err := charsetByName(detected)
+		if err != nil {
+			return nil, detected, err
+		}
+		if enc == nil {
+			return br, detected, nil
+		}
+		return transform.NewReader(br, enc.NewDecoder()), detected, nil
+	}
+
+	enc, err := charsetByName(charset)
+	if err != nil {
+		return nil, charset, err
+	}
+	if enc == nil {
+		return r, "utf-8", nil
+	}
+
+	return transform.NewReader(r, enc.NewDecoder()), charset, nil
+}
+
+// decodeChunk 将raw按charset解码为UTF-8字节，用于watch模式下对增量追加内容的解码。
+// 与wrapCharsetReader不同，这里不做BOM嗅探——增量内容不会再带BOM，
+// 编码应沿用首次全量扫描该文件时探测/指定的结果。
+func decodeChunk(raw []byte, charset string) ([]byte, error) {
+	enc, err := charsetByName(charset)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return raw, nil
+	}
+
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("解码失败: %v", err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+/*
+# @Desc: 把saveResult里原本写死的JSON输出抽成ResultFormatter接口，
+# 按-format参数在json/csv/tsv/parquet/table之间切换，
+# 方便下游分析工具（Spark/DuckDB等）直接消费列式的parquet产物，
+# 或者在终端里快速看一眼对齐的统计表格。
+*/
+
+// ResultFormatter 把统计结果落地为某种具体格式，返回实际写入的文件路径
+type ResultFormatter interface {
+	Format(result *Result, outputFile string, maxOutputSize int64) (string, error)
+}
+
+// newResultFormatter 按-format参数的取值构造对应的ResultFormatter，
+// 空字符串等价于"json"，即原本的默认行为。
+func newResultFormatter(format string) (ResultFormatter, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "json":
+		return jsonFormatter{}, nil
+	case "csv":
+		return &delimitedFormatter{delimiter: ',', ext: ".csv"}, nil
+	case "tsv":
+		return &delimitedFormatter{delimiter: '\t', ext: ".tsv"}, nil
+	case "parquet":
+		return parquetFormatter{}, nil
+	case "table":
+		return consoleTableFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的输出格式: %s (支持: json/csv/tsv/parquet/table)", format)
+	}
+}
+
+// replaceExt 把path的扩展名替换为newExt
+func replaceExt(path, newExt string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + newExt
+}
+
+// jsonFormatter 复用原有的saveResult实现，即默认的JSON输出格式
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(result *Result, outputFile string, maxOutputSize int64) (string, error) {
+	if err := saveResult(result, outputFile, maxOutputSize); err != nil {
+		return "", err
+	}
+	return outputFile, nil
+}
+
+// delimitedFormatter 用于csv/tsv输出，二者只是分隔符和扩展名不同
+type delimitedFormatter struct {
+	delimiter rune
+	ext       string
+}
+
+func (f *delimitedFormatter) Format(result *Result, outputFile string, maxOutputSize int64) (string, error) {
+	path := replaceExt(outputFile, f.ext)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建%s文件失败: %v", f.ext, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Comma = f.delimiter
+
+	if result.Approx {
+		if err := w.Write([]string{"label", "estimated_count", "error_bound"}); err != nil {
+			return "", fmt.Errorf("写入表头失败: %v", err)
+		}
+		for _, item := range result.ApproxTopLabels {
+			row := []string{item.Label, strconv.FormatInt(item.EstimatedCount, 10), strconv.FormatInt(item.ErrorBound, 10)}
+			if err := w.Write(row); err != nil {
+				return "", fmt.Errorf("写入数据行失败: %v", err)
+			}
+		}
+	} else {
+		if err := w.Write([]string{"label", "count"}); err != nil {
+			return "", fmt.Errorf("写入表头失败: %v", err)
+		}
+		for _, lc := range result.SortedLabels {
+			row := []string{lc.Label, strconv.FormatInt(lc.Count, 10)}
+			if err := w.Write(row); err != nil {
+				return "", fmt.Errorf("写入数据行失败: %v", err)
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("写入%s失败: %v", f.ext, err)
+	}
+
+	return path, nil
+}
+
+// consoleTableFormatter 用text/tabwriter把统计结果（精确模式下为全部sorted_labels，
+// approx模式下为ApproxTopLabels）对齐打印到stdout，
+// 同时把同样的文本落盘到outputFile（扩展名替换为.txt），便于保留执行记录。
+type consoleTableFormatter struct{}
+
+func (consoleTableFormatter) Format(result *Result, outputFile string, maxOutputSize int64) (string, error) {
+	path := replaceExt(outputFile, ".txt")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建表格文件失败: %v", err)
+	}
+	defer file.Close()
+
+	tw := tabwriter.NewWriter(file, 0, 4, 2, ' ', 0)
+	writeResultTable(tw, result)
+	if err := tw.Flush(); err != nil {
+		return "", fmt.Errorf("写入表格失败: %v", err)
+	}
+
+	stdoutTw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	writeResultTable(stdoutTw, result)
+	stdoutTw.Flush()
+
+	return path, nil
+}
+
+func writeResultTable(tw *tabwriter.Writer, result *Result) {
+	if result.Approx {
+		fmt.Fprintln(tw, "LABEL\tESTIMATED_COUNT\tERROR_BOUND")
+		for _, item := range result.ApproxTopLabels {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", item.Label, item.EstimatedCount, item.ErrorBound)
+		}
+		return
+	}
+
+	fmt.Fprintln(tw, "LABEL\tCOUNT")
+	for _, lc := range result.SortedLabels {
+		fmt.Fprintf(tw, "%s\t%d\n", lc.Label, lc.Count)
+	}
+}
+
+// parquetLabelRow 是sorted_labels写入parquet时使用的行结构：
+// label和count都走字典编码（重复度高，适合低基数场景）——parquet-go的struct
+// tag并不支持对int64单独指定裸的"rle"编码（schema.go里可选的编码只有
+// plain/dict/delta），而Parquet格式本身的RLE也必须搭配字典使用（即
+// RLE_DICTIONARY），并不存在不带字典的RLE数据页编码。所以这里对count同样
+// 使用dict，才是"count走RLE"在parquet里真正对应的编码方式：字典索引流本身
+// 就是用RLE/bit-packing混合编码写的。列存+字典编码使得Spark/DuckDB等下游
+// 工具按label聚合/过滤时无需整体解压。
+type parquetLabelRow struct {
+	Label string `parquet:"label,dict"`
+	Count int64  `parquet:"count,dict"`
+}
+
+// parquetFormatter 把sorted_labels（或approx模式下的ApproxTopLabels）写成两列的parquet文件
+type parquetFormatter struct{}
+
+func (parquetFormatter) Format(result *Result, outputFile string, maxOutputSize int64) (string, error) {
+	path := replaceExt(outputFile, ".parquet")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建parquet文件失败: %v", err)
+	}
+	defer file.Close()
+
+	rows := make([]parquetLabelRow, 0, len(result.SortedLabels)+len(result.ApproxTopLabels))
+	if result.Approx {
+		for _, item := range result.ApproxTopLabels {
+			rows = append(rows, parquetLabelRow{Label: item.Label, Count: item.EstimatedCount})
+		}
+	} else {
+		for _, lc := range result.SortedLabels {
+			rows = append(rows, parquetLabelRow{Label: lc.Label, Count: lc.Count})
+		}
+	}
+
+	writer := parquet.NewGenericWriter[parquetLabelRow](file)
+	if _, err := writer.Write(rows); err != nil {
+		return "", fmt.Errorf("写入parquet数据失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("关闭parquet writer失败: %v", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,172 @@
+// Package jsonl 提供面向NDJSON/JSONL数据的零拷贝扫描与字段抽取能力。
+//
+// 与 bufio.Scanner+json.Unmarshal 的组合相比，Scanner 直接在底层
+// []byte 缓冲区上定位字段，避免 scanner.Text() 产生的整行拷贝以及
+// 完整反序列化带来的开销，适合统计类场景下的大语料单遍扫描。
+package jsonl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ValueType 标识 ExtractField 返回值在原始JSON中的类型
+type ValueType int
+
+const (
+	TypeNull ValueType = iota
+	TypeString
+	TypeNumber
+	TypeBool
+	TypeObject
+	TypeArray
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeNumber:
+		return "number"
+	case TypeBool:
+		return "bool"
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "array"
+	default:
+		return "null"
+	}
+}
+
+// 默认单行最大长度，超过该长度的行需要通过 SetMaxLineSize 放宽
+const defaultMaxLineSize = 1024 * 1024
+
+// Scanner 在 []byte 上逐行扫描JSONL数据，内部复用一段缓冲区以避免
+// 按行分配。它不是并发安全的，多个worker应各自持有一个Scanner。
+type Scanner struct {
+	r       *bufio.Reader
+	line    []byte // 复用的行缓冲区，Bytes()返回其有效片段
+	err     error
+	maxSize int
+}
+
+// NewScanner 基于 r 创建一个Scanner，r通常是打开的文件或mmap映射出的reader
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:       bufio.NewReaderSize(r, 256*1024),
+		line:    make([]byte, 0, 4096),
+		maxSize: defaultMaxLineSize,
+	}
+}
+
+// SetMaxLineSize 调整单行最大允许长度
+func (s *Scanner) SetMaxLineSize(n int) {
+	s.maxSize = n
+}
+
+// Scan 读取下一行到内部缓冲区，返回是否成功。行尾的换行符不包含在Bytes()中
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.line = s.line[:0]
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		s.line = append(s.line, chunk...)
+		if err == nil {
+			break
+		}
+		if err == bufio.ErrBufferFull {
+			if len(s.line) > s.maxSize {
+				s.err = fmt.Errorf("jsonl: 单行长度超过上限 %d 字节", s.maxSize)
+				return false
+			}
+			continue
+		}
+		if err == io.EOF {
+			if len(s.line) == 0 {
+				s.err = io.EOF
+				return false
+			}
+			break
+		}
+		s.err = err
+		return false
+	}
+
+	// 去掉行尾的 \n 和可能的 \r
+	for len(s.line) > 0 && (s.line[len(s.line)-1] == '\n' || s.line[len(s.line)-1] == '\r') {
+		s.line = s.line[:len(s.line)-1]
+	}
+	return true
+}
+
+// Bytes 返回当前行的底层字节切片，仅在下一次Scan调用前有效
+func (s *Scanner) Bytes() []byte {
+	return s.line
+}
+
+// Err 返回扫描过程中遇到的非io.EOF错误
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// ExtractField 从当前行中抽取name指定的字段，支持形如 "meta.label" 的嵌套路径。
+// 返回的[]byte是底层行缓冲区的切片，调用方若需要跨Scan保留须自行拷贝。
+func (s *Scanner) ExtractField(name []byte) ([]byte, ValueType, error) {
+	return extractPath(s.line, splitPath(name))
+}
+
+// ExtractFields 在一次扫描中抽取多个顶层或嵌套字段，避免为每个key重复扫描整行。
+// names中具有相同顶层段（如 meta.label 与 meta.source）的路径共享对meta对象的定位。
+func (s *Scanner) ExtractFields(names [][]byte) (map[string][]byte, map[string]ValueType, error) {
+	values := make(map[string][]byte, len(names))
+	types := make(map[string]ValueType, len(names))
+
+	// 按顶层字段名分组，使同一顶层对象只被定位一次
+	groups := make(map[string][][]byte)
+	order := make([]string, 0, len(names))
+	for _, n := range names {
+		segs := splitPath(n)
+		top := string(segs[0])
+		if _, ok := groups[top]; !ok {
+			order = append(order, top)
+		}
+		groups[top] = append(groups[top], n)
+	}
+
+	topValues, topTypes, err := scanObject(s.line, order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for top, paths := range groups {
+		topVal, ok := topValues[top]
+		for _, full := range paths {
+			segs := splitPath(full)
+			if len(segs) == 1 {
+				if ok {
+					values[string(full)] = topVal
+					types[string(full)] = topTypes[top]
+				}
+				continue
+			}
+			if !ok || topTypes[top] != TypeObject {
+				continue
+			}
+			v, t, err := extractPath(topVal, segs[1:])
+			if err == nil {
+				values[string(full)] = v
+				types[string(full)] = t
+			}
+		}
+	}
+
+	return values, types, nil
+}
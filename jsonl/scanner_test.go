@@ -0,0 +1,128 @@
+package jsonl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScannerScanStripsNewlineAndCR(t *testing.T) {
+	s := NewScanner(strings.NewReader("{\"a\":1}\r\n{\"a\":2}\n"))
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, string(s.Bytes()))
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{`{"a":1}`, `{"a":2}`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %v", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestScannerScanWithoutTrailingNewline(t *testing.T) {
+	s := NewScanner(strings.NewReader(`{"a":1}`))
+
+	if !s.Scan() {
+		t.Fatalf("Scan() = false, want true for a final line with no trailing newline")
+	}
+	if string(s.Bytes()) != `{"a":1}` {
+		t.Fatalf("Bytes() = %q", s.Bytes())
+	}
+	if s.Scan() {
+		t.Fatalf("Scan() = true after last line, want false")
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil", err)
+	}
+}
+
+func TestExtractFieldTopLevel(t *testing.T) {
+	s := NewScanner(strings.NewReader(`{"label":"cat","score":0.5,"ok":true,"tags":null}`))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false")
+	}
+
+	tests := []struct {
+		field    string
+		wantVal  string
+		wantType ValueType
+	}{
+		{"label", `"cat"`, TypeString},
+		{"score", "0.5", TypeNumber},
+		{"ok", "true", TypeBool},
+		{"tags", "null", TypeNull},
+	}
+
+	for _, tt := range tests {
+		val, typ, err := s.ExtractField([]byte(tt.field))
+		if err != nil {
+			t.Errorf("ExtractField(%q) error: %v", tt.field, err)
+			continue
+		}
+		if string(val) != tt.wantVal || typ != tt.wantType {
+			t.Errorf("ExtractField(%q) = (%q, %v), want (%q, %v)", tt.field, val, typ, tt.wantVal, tt.wantType)
+		}
+	}
+}
+
+func TestExtractFieldNestedPath(t *testing.T) {
+	s := NewScanner(strings.NewReader(`{"meta":{"label":"dog","source":"web"},"other":1}`))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false")
+	}
+
+	val, typ, err := s.ExtractField([]byte("meta.label"))
+	if err != nil {
+		t.Fatalf("ExtractField error: %v", err)
+	}
+	if string(val) != `"dog"` || typ != TypeString {
+		t.Fatalf("ExtractField(meta.label) = (%q, %v), want (\"dog\", string)", val, typ)
+	}
+
+	if _, _, err := s.ExtractField([]byte("meta.missing")); err == nil {
+		t.Fatalf("ExtractField(meta.missing) expected an error for a missing key")
+	}
+}
+
+func TestExtractFieldRejectsTruncatedObject(t *testing.T) {
+	// 模拟一行还在被另一个进程追加写入、尚未写完就被读到的情况：
+	// "label"字段已经写完，但对象后面的部分被截断了。
+	s := NewScanner(strings.NewReader(`{"label":"dog","so`))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false")
+	}
+
+	if _, _, err := s.ExtractField([]byte("label")); err == nil {
+		t.Fatalf("ExtractField(label) error = nil, want an error for a truncated object even though label itself parsed fine")
+	}
+}
+
+func TestExtractFieldsSharesTopLevelLookup(t *testing.T) {
+	s := NewScanner(strings.NewReader(`{"meta":{"label":"cat","source":"web"},"score":0.9}`))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false")
+	}
+
+	values, types, err := s.ExtractFields([][]byte{[]byte("meta.label"), []byte("meta.source"), []byte("score")})
+	if err != nil {
+		t.Fatalf("ExtractFields error: %v", err)
+	}
+
+	if string(values["meta.label"]) != `"cat"` || types["meta.label"] != TypeString {
+		t.Errorf("meta.label = (%q, %v)", values["meta.label"], types["meta.label"])
+	}
+	if string(values["meta.source"]) != `"web"` || types["meta.source"] != TypeString {
+		t.Errorf("meta.source = (%q, %v)", values["meta.source"], types["meta.source"])
+	}
+	if string(values["score"]) != "0.9" || types["score"] != TypeNumber {
+		t.Errorf("score = (%q, %v)", values["score"], types["score"])
+	}
+}
@@ -0,0 +1,222 @@
+package jsonl
+
+import "fmt"
+
+// splitPath 将 "meta.label" 切分为 ["meta", "label"]，不含分隔符的路径返回单元素切片
+func splitPath(name []byte) [][]byte {
+	var segs [][]byte
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			segs = append(segs, name[start:i])
+			start = i + 1
+		}
+	}
+	return segs
+}
+
+// extractPath 在 data 表示的一个JSON对象中按路径逐级下钻定位字段
+func extractPath(data []byte, path [][]byte) ([]byte, ValueType, error) {
+	if len(path) == 0 {
+		return nil, TypeNull, fmt.Errorf("jsonl: 空路径")
+	}
+
+	values, types, err := scanObject(data, []string{string(path[0])})
+	if err != nil {
+		return nil, TypeNull, err
+	}
+
+	val, ok := values[string(path[0])]
+	if !ok {
+		return nil, TypeNull, fmt.Errorf("jsonl: 字段 %q 不存在", path[0])
+	}
+	typ := types[string(path[0])]
+
+	if len(path) == 1 {
+		return val, typ, nil
+	}
+
+	if typ != TypeObject {
+		return nil, TypeNull, fmt.Errorf("jsonl: 字段 %q 不是object，无法继续下钻", path[0])
+	}
+
+	return extractPath(val, path[1:])
+}
+
+// scanObject 对data中第一个JSON对象做单遍扫描，仅为wantKeys中列出的顶层key
+// 保留原始值切片及类型，其余字段只做跳过不分配内存。即使目标字段都已命中，
+// 也会继续扫描到对象结束的'}'为止，以确保对象其余部分是良构的JSON——
+// 不能在命中目标字段后提前返回，否则像被另一进程截断写到一半的行
+// （label字段已经写完、后面的字段还没写完）会被当成合法的一整行静默接受。
+// 调用方必须保证传入的data确实是一整行已经写完的JSON，scanObject本身
+// 不做"这一行是否还在被追加"的判断。
+func scanObject(data []byte, wantKeys []string) (map[string][]byte, map[string]ValueType, error) {
+	values := make(map[string][]byte, len(wantKeys))
+	types := make(map[string]ValueType, len(wantKeys))
+	if len(wantKeys) == 0 {
+		return values, types, nil
+	}
+
+	want := make(map[string]bool, len(wantKeys))
+	remaining := len(wantKeys)
+	for _, k := range wantKeys {
+		want[k] = true
+	}
+
+	i := 0
+	i = skipWhitespace(data, i)
+	if i >= len(data) || data[i] != '{' {
+		return nil, nil, fmt.Errorf("jsonl: 期望对象起始 '{'")
+	}
+	i++
+
+	for {
+		i = skipWhitespace(data, i)
+		if i >= len(data) {
+			return nil, nil, fmt.Errorf("jsonl: 对象未正常闭合")
+		}
+		if data[i] == '}' {
+			break
+		}
+		if data[i] == ',' {
+			i++
+			continue
+		}
+		if data[i] != '"' {
+			return nil, nil, fmt.Errorf("jsonl: 期望字符串key，位置 %d", i)
+		}
+
+		keyStart := i
+		keyEnd, err := skipString(data, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		key := data[keyStart+1 : keyEnd-1]
+		i = keyEnd
+
+		i = skipWhitespace(data, i)
+		if i >= len(data) || data[i] != ':' {
+			return nil, nil, fmt.Errorf("jsonl: 期望 ':' 位置 %d", i)
+		}
+		i++
+		i = skipWhitespace(data, i)
+
+		valStart := i
+		valType, valEnd, err := classifyAndSkip(data, i)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if remaining > 0 && want[string(key)] {
+			values[string(key)] = data[valStart:valEnd]
+			types[string(key)] = valType
+			delete(want, string(key))
+			remaining--
+		}
+
+		i = valEnd
+	}
+
+	return values, types, nil
+}
+
+// classifyAndSkip 识别data[i:]处一个JSON值的类型并跳过该值，返回类型和值结束位置(不含)
+func classifyAndSkip(data []byte, i int) (ValueType, int, error) {
+	if i >= len(data) {
+		return TypeNull, i, fmt.Errorf("jsonl: 意外的输入结尾")
+	}
+
+	switch data[i] {
+	case '"':
+		end, err := skipString(data, i)
+		return TypeString, end, err
+	case '{':
+		end, err := skipBraces(data, i, '{', '}')
+		return TypeObject, end, err
+	case '[':
+		end, err := skipBraces(data, i, '[', ']')
+		return TypeArray, end, err
+	case 't':
+		return skipLiteral(data, i, "true", TypeBool)
+	case 'f':
+		return skipLiteral(data, i, "false", TypeBool)
+	case 'n':
+		return skipLiteral(data, i, "null", TypeNull)
+	default:
+		end := i
+		for end < len(data) && data[end] != ',' && data[end] != '}' && data[end] != ']' && !isWhitespace(data[end]) {
+			end++
+		}
+		if end == i {
+			return TypeNull, i, fmt.Errorf("jsonl: 无法识别的值，位置 %d", i)
+		}
+		return TypeNumber, end, nil
+	}
+}
+
+// skipString 要求data[i]=='"'，返回闭合引号之后的位置
+func skipString(data []byte, i int) (int, error) {
+	if i >= len(data) || data[i] != '"' {
+		return i, fmt.Errorf("jsonl: 期望字符串起始 '\"'，位置 %d", i)
+	}
+	j := i + 1
+	for j < len(data) {
+		if data[j] == '\\' && j+1 < len(data) {
+			j += 2
+			continue
+		}
+		if data[j] == '"' {
+			return j + 1, nil
+		}
+		j++
+	}
+	return j, fmt.Errorf("jsonl: 字符串未闭合")
+}
+
+// skipBraces 跳过一对平衡的括号（对象或数组），考虑括号出现在字符串内部的情况
+func skipBraces(data []byte, i int, open, close byte) (int, error) {
+	if i >= len(data) || data[i] != open {
+		return i, fmt.Errorf("jsonl: 期望 '%c'，位置 %d", open, i)
+	}
+	depth := 0
+	j := i
+	for j < len(data) {
+		switch data[j] {
+		case '"':
+			end, err := skipString(data, j)
+			if err != nil {
+				return j, err
+			}
+			j = end
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+		j++
+	}
+	return j, fmt.Errorf("jsonl: 括号未闭合")
+}
+
+func skipLiteral(data []byte, i int, lit string, t ValueType) (ValueType, int, error) {
+	end := i + len(lit)
+	if end > len(data) || string(data[i:end]) != lit {
+		return TypeNull, i, fmt.Errorf("jsonl: 期望字面量 %q，位置 %d", lit, i)
+	}
+	return t, end, nil
+}
+
+func isWhitespace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func skipWhitespace(data []byte, i int) int {
+	for i < len(data) && isWhitespace(data[i]) {
+		i++
+	}
+	return i
+}
@@ -13,6 +13,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/xiaolonghuster/file_stat_example/approx"
+	"github.com/xiaolonghuster/file_stat_example/jsonl"
 )
 
 /*
@@ -24,25 +27,39 @@ import (
 
 // Config 配置结构体
 type Config struct {
-	LabelKey     string `json:"label_key"`      // 指定的label字段名
-	Workers      int    `json:"workers"`        // 工作goroutine数量
-	BatchSize    int    `json:"batch_size"`     // 批处理大小
-	UseFastParse bool   `json:"use_fast_parse"` // 使用快速解析
-	Output       string `json:"output"`         // 输出文件目录
-	OutputSuffix string `json:"output_suffix"`  // 输出文件后缀
+	LabelKey      string  `json:"label_key"`       // 指定的label字段名
+	Workers       int     `json:"workers"`         // 工作goroutine数量
+	BatchSize     int     `json:"batch_size"`      // 批处理大小
+	UseFastParse  bool    `json:"use_fast_parse"`  // 使用快速解析
+	Output        string  `json:"output"`          // 输出文件目录
+	OutputSuffix  string  `json:"output_suffix"`   // 输出文件后缀
+	Selector      string  `json:"selector"`        // kubectl风格的选择器表达式，空表示不过滤
+	Approx        bool    `json:"approx"`          // 使用近似统计（CMS+Misra-Gries+HLL）代替精确map
+	Epsilon       float64 `json:"epsilon"`         // CMS相对误差参数，越小越精确但内存越大
+	Delta         float64 `json:"delta"`           // CMS误差界限被突破的概率上限
+	MaxOutputSize int64   `json:"max_output_size"` // sorted_labels单个输出分片的字节上限，0表示不滚动
+	Charset       string  `json:"charset"`         // 输入文件编码，空表示不转换，"auto"表示按BOM逐文件嗅探
+	Watch         bool    `json:"watch"`           // 首次全量扫描后是否继续以watch模式监听目录增量
+	Format        string  `json:"format"`          // 输出格式: json(默认)/csv/tsv/parquet/table
 }
 
 // Result 统计结果
 type Result struct {
-	Directory      string           `json:"directory"`
-	FilesProcessed int              `json:"files_processed"`
-	TotalLines     int64            `json:"total_lines"`
-	UniqueLabels   int              `json:"unique_labels"`
-	ProcessingTime float64          `json:"processing_time_seconds"`
-	LinesPerSecond float64          `json:"lines_per_second"`
-	LabelCounts    map[string]int64 `json:"label_counts"`
-	SortedLabels   []LabelCount     `json:"sorted_labels,omitempty"`
-	Errors         []string         `json:"errors,omitempty"`
+	Directory            string             `json:"directory"`
+	FilesProcessed       int                `json:"files_processed"`
+	TotalLines           int64              `json:"total_lines"`        // 过滤前的总行数
+	FilteredLines        int64              `json:"filtered_lines"`     // 通过选择器过滤后参与计数的行数
+	Selector             string             `json:"selector,omitempty"` // 应用的选择器表达式
+	UniqueLabels         int                `json:"unique_labels"`
+	ProcessingTime       float64            `json:"processing_time_seconds"`
+	LinesPerSecond       float64            `json:"lines_per_second"`
+	LabelCounts          map[string]int64   `json:"label_counts,omitempty"`
+	SortedLabels         []LabelCount       `json:"sorted_labels,omitempty"`
+	Errors               []string           `json:"errors,omitempty"`
+	Approx               bool               `json:"approx,omitempty"`
+	UniqueLabelsEstimate uint64             `json:"unique_labels_estimate,omitempty"` // -approx模式下由HLL给出的基数估计
+	ApproxTopLabels      []ApproxLabelCount `json:"approx_top_labels,omitempty"`      // -approx模式下由Misra-Gries+CMS给出的Top-K估计
+	Charsets             map[string]string  `json:"charsets,omitempty"`               // 每个文件实际采用/探测到的编码，key为文件路径
 }
 
 // LabelCount 标签计数结构体
@@ -51,6 +68,14 @@ type LabelCount struct {
 	Count int64  `json:"count"`
 }
 
+// ApproxLabelCount 近似模式下的标签计数，EstimatedCount的真实值不超过该估计值，
+// 误差不超过ErrorBound（即 epsilon*N）
+type ApproxLabelCount struct {
+	Label          string `json:"label"`
+	EstimatedCount int64  `json:"estimated_count"`
+	ErrorBound     int64  `json:"error_bound"`
+}
+
 // Job 工作单元
 type Job struct {
 	FilePath string
@@ -59,79 +84,13 @@ type Job struct {
 
 // WorkerResult worker处理结果
 type WorkerResult struct {
-	Counts map[string]int64
-	Lines  int64
-	Error  error
-}
-
-// 快速解析label（比完整JSON解析快3-5倍）
-func fastExtractLabel(line string) string {
-	// 查找 "label": 位置
-	labelKey := `"label":`
-	pos := strings.Index(line, labelKey)
-	if pos == -1 {
-		return ""
-	}
-
-	// 跳过 "label": 和空白字符
-	start := pos + len(labelKey)
-	for start < len(line) && (line[start] == ' ' || line[start] == '\t' || line[start] == '\n') {
-		start++
-	}
-
-	if start >= len(line) {
-		return ""
-	}
-
-	// 根据值的类型解析
-	firstChar := line[start]
-
-	// 字符串值（双引号）
-	if firstChar == '"' {
-		end := start + 1
-		for end < len(line) && line[end] != '"' {
-			// 处理转义字符
-			if line[end] == '\\' && end+1 < len(line) {
-				end += 2
-				continue
-			}
-			end++
-		}
-		if end < len(line) {
-			return line[start+1 : end]
-		}
-	}
-
-	// 字符串值（单引号）
-	if firstChar == '\'' {
-		end := start + 1
-		for end < len(line) && line[end] != '\'' {
-			if line[end] == '\\' && end+1 < len(line) {
-				end += 2
-				continue
-			}
-			end++
-		}
-		if end < len(line) {
-			return line[start+1 : end]
-		}
-	}
-
-	// 数字或其他简单值
-	end := start
-	for end < len(line) && line[end] != ',' && line[end] != '}' && line[end] != '\n' {
-		end++
-	}
-
-	value := strings.TrimSpace(line[start:end])
-
-	// 去除可能的引号
-	if len(value) >= 2 && ((value[0] == '"' && value[len(value)-1] == '"') ||
-		(value[0] == '\'' && value[len(value)-1] == '\'')) {
-		value = value[1 : len(value)-1]
-	}
-
-	return value
+	Counts        map[string]int64 // 精确模式下的计数，近似模式下为nil
+	Sketches      *approx.Sketches // 近似模式下的本地摘要，精确模式下为nil
+	TotalLines    int64            // 过滤前扫描到的有效行数
+	FilteredLines int64            // 通过选择器后计入统计的行数
+	FilePath      string           // 本次处理的文件路径，用于汇总Result.Charsets
+	Charset       string           // 该文件实际采用/探测到的编码
+	Error         error
 }
 
 // 确保目录存在，如果不存在则创建
@@ -209,18 +168,66 @@ func getOutputFilePath(directory, outputDir, suffix string) (string, error) {
 	return fileName, nil
 }
 
-// 处理单个文件
-func processFile(filePath, labelKey string, fastParse bool) (map[string]int64, int64, error) {
+// 处理单个文件，sel为nil或空选择器表示不过滤，所有抽取到label的行都计入Counts。
+// charset为空表示不做编码转换，为"auto"时按BOM嗅探，否则按指定编码解码为UTF-8；
+// detectedCharset返回实际采用的编码名称，供调用方记录到metadata中。
+func processFile(filePath, labelKey string, fastParse bool, sel *Selector, charset string) (counts map[string]int64, totalLines int64, filteredLines int64, detectedCharset string, err error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, 0, fmt.Errorf("打开文件失败: %v", err)
+		return nil, 0, 0, "", fmt.Errorf("打开文件失败: %v", err)
 	}
 	defer file.Close()
 
-	counts := make(map[string]int64)
-	var lineCount int64
+	reader, detectedCharset, err := wrapCharsetReader(file, charset)
+	if err != nil {
+		return nil, 0, 0, detectedCharset, fmt.Errorf("解析charset失败[%s]: %v", filePath, err)
+	}
+
+	counts = make(map[string]int64)
+
+	if fastParse {
+		selKeys := make([][]byte, 0, len(sel.Keys())+1)
+		selKeys = append(selKeys, []byte(labelKey))
+		for _, k := range sel.Keys() {
+			selKeys = append(selKeys, []byte(k))
+		}
+
+		scanner := jsonl.NewScanner(reader)
+		scanner.SetMaxLineSize(10 * 1024 * 1024) // 最大10MB的行
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			values, types, err := scanner.ExtractFields(selKeys)
+			if err != nil {
+				continue
+			}
+
+			label := stringifyJSONLValue(values[labelKey], types[labelKey])
+			if label == "" {
+				continue
+			}
+			totalLines++
+
+			if !sel.Matches(jsonlValuesToStrings(values, types)) {
+				continue
+			}
+
+			counts[label]++
+			filteredLines++
+		}
+
+		if err := scanner.Err(); err != nil {
+			return counts, totalLines, filteredLines, detectedCharset, fmt.Errorf("读取文件失败[%s]: %v", filePath, err)
+		}
 
-	scanner := bufio.NewScanner(file)
+		return counts, totalLines, filteredLines, detectedCharset, nil
+	}
+
+	// 完整JSON解析
+	scanner := bufio.NewScanner(reader)
 	buf := make([]byte, 0, 256*1024)  // 256KB缓冲区
 	scanner.Buffer(buf, 10*1024*1024) // 最大10MB的行
 
@@ -231,55 +238,202 @@ func processFile(filePath, labelKey string, fastParse bool) (map[string]int64, i
 		}
 
 		var label string
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &data); err != nil {
+			continue // 跳过无效JSON行
+		}
 
-		if fastParse {
-			label = fastExtractLabel(line)
-		} else {
-			// 完整JSON解析
-			var data map[string]interface{}
-			if err := json.Unmarshal([]byte(line), &data); err != nil {
-				continue // 跳过无效JSON行
+		if labelVal, ok := data[labelKey]; ok {
+			label = stringifyJSONValue(labelVal)
+		}
+
+		if label == "" {
+			continue
+		}
+		totalLines++
+
+		if !sel.Matches(stringifyJSONFields(data)) {
+			continue
+		}
+
+		counts[label]++
+		filteredLines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return counts, totalLines, filteredLines, detectedCharset, fmt.Errorf("读取文件失败[%s]: %v", filePath, err)
+	}
+
+	return counts, totalLines, filteredLines, detectedCharset, nil
+}
+
+// stringifyJSONValue 将完整JSON解析得到的interface{}值转成选择器比较用的字符串
+func stringifyJSONValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return fmt.Sprintf("%g", val)
+	case bool:
+		return fmt.Sprintf("%v", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// stringifyJSONFields 将data中的顶层字段转成选择器可用的 key->字符串 映射
+func stringifyJSONFields(data map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		fields[k] = stringifyJSONValue(v)
+	}
+	return fields
+}
+
+// stringifyJSONLValue 将jsonl.Scanner抽取出的原始值转成用于计数/比较的字符串
+func stringifyJSONLValue(value []byte, valType jsonl.ValueType) string {
+	if value == nil {
+		return ""
+	}
+	if valType == jsonl.TypeString && len(value) >= 2 {
+		return string(value[1 : len(value)-1])
+	}
+	return string(value)
+}
+
+// jsonlValuesToStrings 批量转换ExtractFields的结果，供Selector.Matches使用
+func jsonlValuesToStrings(values map[string][]byte, types map[string]jsonl.ValueType) map[string]string {
+	fields := make(map[string]string, len(values))
+	for k, v := range values {
+		fields[k] = stringifyJSONLValue(v, types[k])
+	}
+	return fields
+}
+
+// processFileApprox 与processFile类似，但不构建精确的map[string]int64，而是把每个
+// 命中的label写入sketches（CMS+Misra-Gries+HLL），用于-approx模式下的超大label空间场景。
+func processFileApprox(filePath, labelKey string, fastParse bool, sel *Selector, sketches *approx.Sketches, charset string) (totalLines int64, filteredLines int64, detectedCharset string, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	reader, detectedCharset, err := wrapCharsetReader(file, charset)
+	if err != nil {
+		return 0, 0, detectedCharset, fmt.Errorf("解析charset失败[%s]: %v", filePath, err)
+	}
+
+	if fastParse {
+		selKeys := make([][]byte, 0, len(sel.Keys())+1)
+		selKeys = append(selKeys, []byte(labelKey))
+		for _, k := range sel.Keys() {
+			selKeys = append(selKeys, []byte(k))
+		}
+
+		scanner := jsonl.NewScanner(reader)
+		scanner.SetMaxLineSize(10 * 1024 * 1024)
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
 			}
 
-			if labelVal, ok := data[labelKey]; ok {
-				switch v := labelVal.(type) {
-				case string:
-					label = v
-				case float64:
-					label = fmt.Sprintf("%g", v)
-				case int:
-					label = fmt.Sprintf("%d", v)
-				case bool:
-					label = fmt.Sprintf("%v", v)
-				default:
-					label = fmt.Sprintf("%v", v)
-				}
+			values, types, err := scanner.ExtractFields(selKeys)
+			if err != nil {
+				continue
+			}
+
+			label := stringifyJSONLValue(values[labelKey], types[labelKey])
+			if label == "" {
+				continue
+			}
+			totalLines++
+
+			if !sel.Matches(jsonlValuesToStrings(values, types)) {
+				continue
 			}
+
+			sketches.Add(label)
+			filteredLines++
 		}
 
-		if label != "" {
-			counts[label]++
-			lineCount++
+		if err := scanner.Err(); err != nil {
+			return totalLines, filteredLines, detectedCharset, fmt.Errorf("读取文件失败[%s]: %v", filePath, err)
+		}
+
+		return totalLines, filteredLines, detectedCharset, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	buf := make([]byte, 0, 256*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		var label string
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &data); err != nil {
+			continue
+		}
+
+		if labelVal, ok := data[labelKey]; ok {
+			label = stringifyJSONValue(labelVal)
+		}
+
+		if label == "" {
+			continue
 		}
+		totalLines++
+
+		if !sel.Matches(stringifyJSONFields(data)) {
+			continue
+		}
+
+		sketches.Add(label)
+		filteredLines++
 	}
 
 	if err := scanner.Err(); err != nil {
-		return counts, lineCount, fmt.Errorf("读取文件失败[%s]: %v", filePath, err)
+		return totalLines, filteredLines, detectedCharset, fmt.Errorf("读取文件失败[%s]: %v", filePath, err)
 	}
 
-	return counts, lineCount, nil
+	return totalLines, filteredLines, detectedCharset, nil
 }
 
-// worker处理文件
-func worker(id int, jobs <-chan Job, results chan<- WorkerResult, fastParse bool, wg *sync.WaitGroup) {
+// worker处理文件。useApprox为true时每个worker持有独立的Sketches，
+// 用epsilon/delta构造出的CMS与其它worker的CMS使用相同的哈希系数，可直接合并。
+// charset为空表示不做编码转换，为"auto"时按文件逐个嗅探BOM。
+func worker(id int, jobs <-chan Job, results chan<- WorkerResult, fastParse bool, sel *Selector, useApprox bool, epsilon, delta float64, charset string, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for job := range jobs {
-		counts, lines, err := processFile(job.FilePath, job.LabelKey, fastParse)
+		if useApprox {
+			sketches := approx.NewSketches(epsilon, delta, approx.DefaultHeavyHittersK, approx.DefaultHLLPrecision)
+			total, filtered, detected, err := processFileApprox(job.FilePath, job.LabelKey, fastParse, sel, sketches, charset)
+			results <- WorkerResult{
+				Sketches:      sketches,
+				TotalLines:    total,
+				FilteredLines: filtered,
+				FilePath:      job.FilePath,
+				Charset:       detected,
+				Error:         err,
+			}
+			continue
+		}
+
+		counts, total, filtered, detected, err := processFile(job.FilePath, job.LabelKey, fastParse, sel, charset)
 		results <- WorkerResult{
-			Counts: counts,
-			Lines:  lines,
-			Error:  err,
+			Counts:        counts,
+			TotalLines:    total,
+			FilteredLines: filtered,
+			FilePath:      job.FilePath,
+			Charset:       detected,
+			Error:         err,
 		}
 	}
 }
@@ -310,6 +464,11 @@ func collectJSONLFiles(directory string) ([]string, error) {
 func countLabels(config Config, directory string) (*Result, error) {
 	startTime := time.Now()
 
+	sel, err := ParseSelector(config.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("解析选择器失败: %v", err)
+	}
+
 	// 收集文件
 	files, err := collectJSONLFiles(directory)
 	if err != nil {
@@ -323,6 +482,9 @@ func countLabels(config Config, directory string) (*Result, error) {
 	fmt.Printf("找到 %d 个JSONL/JSON文件\n", len(files))
 	fmt.Printf("使用 %d 个worker\n", config.Workers)
 	fmt.Printf("快速解析模式: %v\n", config.UseFastParse)
+	if !sel.Empty() {
+		fmt.Printf("选择器: %s\n", config.Selector)
+	}
 
 	// 创建工作通道
 	jobs := make(chan Job, len(files))
@@ -332,7 +494,7 @@ func countLabels(config Config, directory string) (*Result, error) {
 	var wg sync.WaitGroup
 	for i := 0; i < config.Workers; i++ {
 		wg.Add(1)
-		go worker(i, jobs, results, config.UseFastParse, &wg)
+		go worker(i, jobs, results, config.UseFastParse, sel, config.Approx, config.Epsilon, config.Delta, config.Charset, &wg)
 	}
 
 	// 发送工作
@@ -351,8 +513,11 @@ func countLabels(config Config, directory string) (*Result, error) {
 
 	// 收集结果
 	totalCounts := make(map[string]int64)
+	var globalSketches *approx.Sketches
 	var totalLines int64
+	var filteredLines int64
 	var errors []string
+	charsets := make(map[string]string)
 
 	for result := range results {
 		if result.Error != nil {
@@ -360,15 +525,58 @@ func countLabels(config Config, directory string) (*Result, error) {
 			continue
 		}
 
-		for label, count := range result.Counts {
-			totalCounts[label] += count
+		if result.FilePath != "" {
+			charsets[result.FilePath] = result.Charset
+		}
+
+		if config.Approx {
+			if globalSketches == nil {
+				globalSketches = result.Sketches
+			} else if err := globalSketches.Merge(result.Sketches); err != nil {
+				errors = append(errors, err.Error())
+			}
+		} else {
+			for label, count := range result.Counts {
+				totalCounts[label] += count
+			}
 		}
 
-		totalLines += result.Lines
+		totalLines += result.TotalLines
+		filteredLines += result.FilteredLines
 	}
 
 	processingTime := time.Since(startTime).Seconds()
 
+	result := &Result{
+		Directory:      directory,
+		FilesProcessed: len(files),
+		TotalLines:     totalLines,
+		FilteredLines:  filteredLines,
+		Selector:       config.Selector,
+		ProcessingTime: processingTime,
+		LinesPerSecond: float64(filteredLines) / processingTime,
+		Errors:         errors,
+		Approx:         config.Approx,
+		Charsets:       charsets,
+	}
+
+	if config.Approx {
+		result.UniqueLabelsEstimate = globalSketches.HLL.Estimate()
+		result.UniqueLabels = int(result.UniqueLabelsEstimate)
+
+		top := globalSketches.HeavyHitters.TopN(10)
+		errorBound := globalSketches.CMS.ErrorBound(filteredLines)
+		result.ApproxTopLabels = make([]ApproxLabelCount, 0, len(top))
+		for _, item := range top {
+			result.ApproxTopLabels = append(result.ApproxTopLabels, ApproxLabelCount{
+				Label:          item.Key,
+				EstimatedCount: globalSketches.CMS.Estimate(item.Key),
+				ErrorBound:     errorBound,
+			})
+		}
+		return result, nil
+	}
+
 	// 创建排序后的标签列表
 	var sortedLabels []LabelCount
 	for label, count := range totalCounts {
@@ -386,41 +594,61 @@ func countLabels(config Config, directory string) (*Result, error) {
 		return sortedLabels[i].Count > sortedLabels[j].Count
 	})
 
-	return &Result{
-		Directory:      directory,
-		FilesProcessed: len(files),
-		TotalLines:     totalLines,
-		UniqueLabels:   len(totalCounts),
-		ProcessingTime: processingTime,
-		LinesPerSecond: float64(totalLines) / processingTime,
-		LabelCounts:    totalCounts,
-		SortedLabels:   sortedLabels,
-		Errors:         errors,
-	}, nil
+	result.UniqueLabels = len(totalCounts)
+	result.LabelCounts = totalCounts
+	result.SortedLabels = sortedLabels
+
+	return result, nil
 }
 
-// 保存结果为JSON
-func saveResult(result *Result, outputFile string) error {
+// 保存结果为JSON。maxOutputSize>0时，sorted_labels改为通过RotatingResultWriter
+// 以NDJSON形式流式写到outputFile同目录下的编号分片中，避免label种类极多时
+// 在内存里拼出一个巨大的JSON数组；metadata中记录分片文件列表供下游读取。
+func saveResult(result *Result, outputFile string, maxOutputSize int64) error {
 	// 为了更好的可读性，添加一些格式
 	formatted := map[string]interface{}{
 		"metadata": map[string]interface{}{
 			"directory":         result.Directory,
 			"files_processed":   result.FilesProcessed,
 			"total_lines":       result.TotalLines,
+			"filtered_lines":    result.FilteredLines,
+			"selector":          result.Selector,
 			"unique_labels":     result.UniqueLabels,
 			"processing_time_s": result.ProcessingTime,
 			"lines_per_second":  result.LinesPerSecond,
 			"errors_count":      len(result.Errors),
+			"approx":            result.Approx,
 			"timestamp":         time.Now().Format("2006-01-02 15:04:05"),
 		},
-		"label_counts":  result.LabelCounts,
-		"sorted_labels": result.SortedLabels,
+	}
+
+	switch {
+	case result.Approx:
+		formatted["metadata"].(map[string]interface{})["unique_labels_estimate"] = result.UniqueLabelsEstimate
+		formatted["approx_top_labels"] = result.ApproxTopLabels
+
+	case maxOutputSize > 0 && len(result.SortedLabels) > 0:
+		// 精确的label_counts与sorted_labels分片里的数据完全相同，这里不再把它也塞进
+		// 主JSON文件，否则-max-output-size就失去了控制内存/文件体积的意义。
+		shards, err := writeSortedLabelsRotating(result.SortedLabels, outputFile, maxOutputSize)
+		if err != nil {
+			return err
+		}
+		formatted["metadata"].(map[string]interface{})["sorted_labels_shards"] = shards
+
+	default:
+		formatted["label_counts"] = result.LabelCounts
+		formatted["sorted_labels"] = result.SortedLabels
 	}
 
 	if len(result.Errors) > 0 {
 		formatted["errors"] = result.Errors
 	}
 
+	if len(result.Charsets) > 0 {
+		formatted["metadata"].(map[string]interface{})["charsets"] = result.Charsets
+	}
+
 	data, err := json.MarshalIndent(formatted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化JSON失败: %v", err)
@@ -439,8 +667,15 @@ func printSummary(result *Result, outputFile string) {
 	fmt.Println("统计完成!")
 	fmt.Printf("输出文件: %s\n", outputFile)
 	fmt.Printf("处理了 %d 个文件\n", result.FilesProcessed)
-	fmt.Printf("共处理了 %d 行数据\n", result.TotalLines)
-	fmt.Printf("发现 %d 个不同的label\n", result.UniqueLabels)
+	fmt.Printf("共扫描了 %d 行数据（过滤前）\n", result.TotalLines)
+	if result.Selector != "" {
+		fmt.Printf("选择器 %q 过滤后计入统计 %d 行\n", result.Selector, result.FilteredLines)
+	}
+	if result.Approx {
+		fmt.Printf("近似模式: 约 %d 个不同的label（HyperLogLog估计）\n", result.UniqueLabelsEstimate)
+	} else {
+		fmt.Printf("发现 %d 个不同的label\n", result.UniqueLabels)
+	}
 	fmt.Printf("处理时间: %.2f 秒\n", result.ProcessingTime)
 	fmt.Printf("处理速度: %.0f 行/秒\n", result.LinesPerSecond)
 
@@ -457,7 +692,21 @@ func printSummary(result *Result, outputFile string) {
 	}
 
 	// 显示Top 10标签
-	if len(result.SortedLabels) > 0 {
+	if result.Approx {
+		if len(result.ApproxTopLabels) > 0 {
+			fmt.Println("\nTop 10 最常见的label（近似估计，误差不超过±error_bound）:")
+			for i, item := range result.ApproxTopLabels {
+				if i >= 10 {
+					break
+				}
+				label := item.Label
+				if len(label) > 50 {
+					label = label[:47] + "..."
+				}
+				fmt.Printf("  %2d. %-50s : %10d (±%d)\n", i+1, label, item.EstimatedCount, item.ErrorBound)
+			}
+		}
+	} else if len(result.SortedLabels) > 0 {
 		fmt.Println("\nTop 10 最常见的label:")
 
 		for i := 0; i < len(result.SortedLabels) && i < 10; i++ {
@@ -487,12 +736,33 @@ func showUsage() {
 	fmt.Println("  -workers <数量>     worker数量 (默认: CPU核心数*2)")
 	fmt.Println("  -suffix <后缀>      输出文件后缀 (默认: _label_stats.json)")
 	fmt.Println("  -full-parse         使用完整JSON解析 (默认使用快速解析)")
+	fmt.Println("  -selector <表达式>   kubectl风格的选择器，只统计匹配的记录")
+	fmt.Println("                      例如: \"label in (cat,dog),source!=web,score>0.5\"")
+	fmt.Println("  -approx             使用近似统计(CMS+Misra-Gries+HLL)代替精确map，适合超大label空间")
+	fmt.Println("  -epsilon <浮点数>   approx模式下CMS的相对误差 (默认: 0.001)")
+	fmt.Println("  -delta <浮点数>     approx模式下CMS误差界限被突破的概率上限 (默认: 0.01)")
+	fmt.Println("  -max-output-size <大小>  sorted_labels单个输出分片上限，如 100MB (默认: 不滚动)")
+	fmt.Println("  -charset <名称|auto>  输入文件编码，如 gbk/gb18030/big5 (默认: utf-8，不转换)")
+	fmt.Println("                      auto表示逐文件按BOM嗅探编码")
+	fmt.Println("                      注意: GBK/Big5等中文编码的文件几乎从不带BOM，auto会把")
+	fmt.Println("                      它们误判为utf-8，解析出来是乱码；这类数据请显式指定")
+	fmt.Println("                      -charset gbk/big5等，不要依赖auto")
+	fmt.Println("  -watch              首次全量扫描后不退出，持续监听目录增量并输出NDJSON事件流")
+	fmt.Println("  -format <格式>      输出格式: json(默认)/csv/tsv/parquet/table")
 	fmt.Println("  -help               显示此帮助信息")
 	fmt.Println()
 	fmt.Println("示例:")
 	fmt.Println("  jsonl-counter ./data")
 	fmt.Println("  jsonl-counter /path/to/dataset -workers 8 -suffix _statistics.json")
 	fmt.Println("  jsonl-counter ./logs -full-parse")
+	fmt.Println("  jsonl-counter ./data -selector \"label in (cat,dog),score>0.5\"")
+	fmt.Println("  jsonl-counter ./huge-dataset -approx -epsilon 0.0005")
+	fmt.Println("  jsonl-counter ./huge-dataset -max-output-size 100MB")
+	fmt.Println("  jsonl-counter ./legacy-data -charset gbk")
+	fmt.Println("  jsonl-counter ./mixed-data -charset auto")
+	fmt.Println("  jsonl-counter ./incoming -watch")
+	fmt.Println("  jsonl-counter ./data -format csv")
+	fmt.Println("  jsonl-counter ./huge-dataset -format parquet")
 }
 
 func main() {
@@ -516,6 +786,8 @@ func main() {
 		UseFastParse: true, // 默认使用快速解析
 		Output:       "/Users/lixiaolong/tmp/20260118/file_stat/",
 		OutputSuffix: "_label_stats.json",
+		Epsilon:      0.001,
+		Delta:        0.01,
 	}
 
 	// 解析参数
@@ -550,6 +822,53 @@ func main() {
 			}
 		case "-full-parse":
 			config.UseFastParse = false
+		case "-selector":
+			if i+1 < len(args) {
+				config.Selector = args[i+1]
+				i++
+			}
+		case "-approx":
+			config.Approx = true
+		case "-epsilon":
+			if i+1 < len(args) {
+				var epsilon float64
+				_, err := fmt.Sscanf(args[i+1], "%f", &epsilon)
+				if err == nil && epsilon > 0 {
+					config.Epsilon = epsilon
+				}
+				i++
+			}
+		case "-delta":
+			if i+1 < len(args) {
+				var delta float64
+				_, err := fmt.Sscanf(args[i+1], "%f", &delta)
+				if err == nil && delta > 0 {
+					config.Delta = delta
+				}
+				i++
+			}
+		case "-max-output-size":
+			if i+1 < len(args) {
+				size, err := parseSize(args[i+1])
+				if err != nil {
+					fmt.Printf("警告: 无效的 -max-output-size 参数 %q: %v\n", args[i+1], err)
+				} else {
+					config.MaxOutputSize = size
+				}
+				i++
+			}
+		case "-charset":
+			if i+1 < len(args) {
+				config.Charset = args[i+1]
+				i++
+			}
+		case "-watch":
+			config.Watch = true
+		case "-format":
+			if i+1 < len(args) {
+				config.Format = args[i+1]
+				i++
+			}
 		default:
 			fmt.Printf("警告: 未知参数 %s\n", args[i])
 		}
@@ -576,6 +895,13 @@ func main() {
 	}
 	fmt.Printf("输出文件: %s\n", outputFile)
 
+	if config.Watch {
+		if err := runWatch(config, directory, outputFile); err != nil {
+			log.Fatalf("watch模式运行失败: %v", err)
+		}
+		return
+	}
+
 	// 执行统计
 	result, err := countLabels(config, directory)
 	if err != nil {
@@ -583,9 +909,15 @@ func main() {
 	}
 
 	// 保存结果
-	if err := saveResult(result, outputFile); err != nil {
+	formatter, err := newResultFormatter(config.Format)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	savedFile, err := formatter.Format(result, outputFile, config.MaxOutputSize)
+	if err != nil {
 		log.Fatalf("保存结果失败: %v", err)
 	}
+	outputFile = savedFile
 
 	// 打印摘要
 	printSummary(result, outputFile)
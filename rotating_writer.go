@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+# @Desc: 受外部滚动日志writer模式启发的结果输出器：当label种类达到百万级时，
+# 一次性拼出的sorted_labels JSON数组会占用大量内存，RotatingResultWriter
+# 改为将其按NDJSON逐行流式写出，并在单个分片达到-max-output-size时
+# 滚动到下一个编号文件，天然支持中断后继续写入。
+*/
+
+// defaultMaxOutputSize 是NewRotatingResultWriter收到非正的maxSize时使用的单分片
+// 大小上限；未指定-max-output-size时saveResult不走滚动输出，不会用到该值
+const defaultMaxOutputSize = 100 * 1024 * 1024
+
+// RotatingResultWriter 将LabelCount以NDJSON形式流式写入baseName.jsonl，
+// 超过maxSize后滚动到baseName.1.jsonl、baseName.2.jsonl……
+type RotatingResultWriter struct {
+	baseName string
+	maxSize  int64
+	index    int
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+}
+
+// NewRotatingResultWriter 创建滚动writer。若baseName的分片已存在（例如上次运行中断），
+// 会从最后一个未写满的分片继续追加，使得统计任务具备可恢复性。
+func NewRotatingResultWriter(baseName string, maxSize int64) (*RotatingResultWriter, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxOutputSize
+	}
+
+	w := &RotatingResultWriter{baseName: baseName, maxSize: maxSize}
+
+	index := 0
+	for {
+		if _, err := os.Stat(w.pathFor(index + 1)); err != nil {
+			break
+		}
+		index++
+	}
+	w.index = index
+
+	if err := w.openCurrent(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingResultWriter) pathFor(index int) string {
+	if index == 0 {
+		return w.baseName + ".jsonl"
+	}
+	return fmt.Sprintf("%s.%d.jsonl", w.baseName, index)
+}
+
+func (w *RotatingResultWriter) openCurrent() error {
+	path := w.pathFor(w.index)
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开分片文件失败[%s]: %v", path, err)
+	}
+
+	w.file = f
+	w.writer = bufio.NewWriter(f)
+	w.size = size
+	return nil
+}
+
+// WriteLabel 写入一行NDJSON，必要时先滚动到下一个分片再写入
+func (w *RotatingResultWriter) WriteLabel(lc LabelCount) error {
+	data, err := json.Marshal(lc)
+	if err != nil {
+		return fmt.Errorf("序列化label失败: %v", err)
+	}
+	data = append(data, '\n')
+
+	if w.size > 0 && w.size+int64(len(data)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.writer.Write(data)
+	if err != nil {
+		return fmt.Errorf("写入分片文件失败: %v", err)
+	}
+	w.size += int64(n)
+	return nil
+}
+
+func (w *RotatingResultWriter) rotate() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.index++
+	return w.openCurrent()
+}
+
+// Close 落盘并关闭当前持有的分片文件
+func (w *RotatingResultWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+// Shards 返回从0到当前分片为止，已经被写入过的全部分片文件路径
+func (w *RotatingResultWriter) Shards() []string {
+	shards := make([]string, 0, w.index+1)
+	for i := 0; i <= w.index; i++ {
+		shards = append(shards, w.pathFor(i))
+	}
+	return shards
+}
+
+// writeSortedLabelsRotating 将sorted_labels写入以outputFile（去掉扩展名）为基础名的
+// NDJSON分片中，返回写入过的全部分片路径
+func writeSortedLabelsRotating(labels []LabelCount, outputFile string, maxOutputSize int64) ([]string, error) {
+	base := strings.TrimSuffix(outputFile, filepath.Ext(outputFile))
+
+	w, err := NewRotatingResultWriter(base, maxOutputSize)
+	if err != nil {
+		return nil, fmt.Errorf("创建滚动输出writer失败: %v", err)
+	}
+
+	for _, lc := range labels {
+		if err := w.WriteLabel(lc); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("关闭滚动输出writer失败: %v", err)
+	}
+
+	return w.Shards(), nil
+}
+
+// parseSize 解析形如 "100MB"、"512KB"、"2GB" 或纯字节数的大小字符串
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("大小参数为空")
+	}
+
+	upper := strings.ToUpper(s)
+	multiplier := int64(1)
+	numPart := upper
+
+	switch {
+	case strings.HasSuffix(upper, "GB"):
+		multiplier = 1024 * 1024 * 1024
+		numPart = strings.TrimSuffix(upper, "GB")
+	case strings.HasSuffix(upper, "MB"):
+		multiplier = 1024 * 1024
+		numPart = strings.TrimSuffix(upper, "MB")
+	case strings.HasSuffix(upper, "KB"):
+		multiplier = 1024
+		numPart = strings.TrimSuffix(upper, "KB")
+	case strings.HasSuffix(upper, "B"):
+		numPart = strings.TrimSuffix(upper, "B")
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(numPart), 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析大小 %q: %v", s, err)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
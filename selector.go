@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+# @Desc: kubectl风格的label选择器，语法参考kubectl的LabelSelector/FieldSelector，
+# 支持形如 `label in (cat,dog),source!=web,score>0.5` 的组合表达式。
+*/
+
+// SelectorOp 选择器单个条件使用的比较操作符
+type SelectorOp int
+
+const (
+	OpIn SelectorOp = iota
+	OpNotIn
+	OpEquals
+	OpNotEquals
+	OpExists
+	OpNotExists
+	OpGreater
+	OpLess
+)
+
+// Requirement 选择器中的单个条件，例如 "source!=web" 或 "score>0.5"
+type Requirement struct {
+	Key    string
+	Op     SelectorOp
+	Values []string // OpIn/OpNotIn/OpEquals/OpNotEquals使用
+	Number float64  // OpGreater/OpLess使用
+}
+
+// Selector 由多个Requirement以“与”关系组成
+type Selector struct {
+	requirements []Requirement
+}
+
+// ParseSelector 解析形如 "label in (cat,dog),source!=web,score>0.5" 的选择器表达式
+func ParseSelector(expr string) (*Selector, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return &Selector{}, nil
+	}
+
+	clauses, err := splitClauses(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := &Selector{}
+	for _, clause := range clauses {
+		req, err := parseRequirement(clause)
+		if err != nil {
+			return nil, fmt.Errorf("解析选择器条件 %q 失败: %v", clause, err)
+		}
+		sel.requirements = append(sel.requirements, req)
+	}
+
+	return sel, nil
+}
+
+// splitClauses 按顶层逗号切分表达式，括号内的逗号（如 in (a,b,c)）不作为分隔符
+func splitClauses(expr string) ([]string, error) {
+	var clauses []string
+	depth := 0
+	start := 0
+
+	for i, ch := range expr {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("选择器括号不匹配")
+			}
+		case ',':
+			if depth == 0 {
+				clauses = append(clauses, strings.TrimSpace(expr[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("选择器括号不匹配")
+	}
+	clauses = append(clauses, strings.TrimSpace(expr[start:]))
+
+	return clauses, nil
+}
+
+func parseRequirement(clause string) (Requirement, error) {
+	switch {
+	case strings.Contains(clause, "!="):
+		parts := strings.SplitN(clause, "!=", 2)
+		return Requirement{Key: strings.TrimSpace(parts[0]), Op: OpNotEquals, Values: []string{strings.TrimSpace(parts[1])}}, nil
+
+	case strings.Contains(clause, ">"):
+		parts := strings.SplitN(clause, ">", 2)
+		n, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return Requirement{}, fmt.Errorf("无效的数值比较: %v", err)
+		}
+		return Requirement{Key: strings.TrimSpace(parts[0]), Op: OpGreater, Number: n}, nil
+
+	case strings.Contains(clause, "<"):
+		parts := strings.SplitN(clause, "<", 2)
+		n, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return Requirement{}, fmt.Errorf("无效的数值比较: %v", err)
+		}
+		return Requirement{Key: strings.TrimSpace(parts[0]), Op: OpLess, Number: n}, nil
+
+	case strings.Contains(clause, "=="):
+		parts := strings.SplitN(clause, "==", 2)
+		return Requirement{Key: strings.TrimSpace(parts[0]), Op: OpEquals, Values: []string{strings.TrimSpace(parts[1])}}, nil
+
+	case strings.Contains(clause, "="):
+		parts := strings.SplitN(clause, "=", 2)
+		return Requirement{Key: strings.TrimSpace(parts[0]), Op: OpEquals, Values: []string{strings.TrimSpace(parts[1])}}, nil
+
+	case strings.HasPrefix(clause, "!"):
+		return Requirement{Key: strings.TrimSpace(clause[1:]), Op: OpNotExists}, nil
+	}
+
+	// "key in (a,b)" / "key notin (a,b)"
+	if idx := strings.Index(clause, " in ("); idx != -1 && strings.HasSuffix(clause, ")") {
+		key := strings.TrimSpace(clause[:idx])
+		values := splitValues(clause[idx+len(" in (") : len(clause)-1])
+		return Requirement{Key: key, Op: OpIn, Values: values}, nil
+	}
+	if idx := strings.Index(clause, " notin ("); idx != -1 && strings.HasSuffix(clause, ")") {
+		key := strings.TrimSpace(clause[:idx])
+		values := splitValues(clause[idx+len(" notin (") : len(clause)-1])
+		return Requirement{Key: key, Op: OpNotIn, Values: values}, nil
+	}
+
+	// 条件里出现了括号，却不是合法的 "key in (...)" / "key notin (...)" 形式
+	// （例如漏写了in/notin后面的空格），不能放过当成裸key存在性检查，否则会
+	// 悄悄地把拼写错误的选择器变成一个永远不匹配的条件。
+	if strings.ContainsAny(clause, "()") {
+		return Requirement{}, fmt.Errorf("无法识别的条件，期望 \"key in (a,b)\" 或 \"key notin (a,b)\" 形式（注意in/notin前后需要空格）")
+	}
+
+	// 裸key表示“字段存在”
+	if clause != "" {
+		return Requirement{Key: clause, Op: OpExists}, nil
+	}
+
+	return Requirement{}, fmt.Errorf("无法识别的条件")
+}
+
+func splitValues(s string) []string {
+	parts := strings.Split(s, ",")
+	values := make([]string, 0, len(parts))
+	for _, p := range parts {
+		values = append(values, strings.TrimSpace(p))
+	}
+	return values
+}
+
+// Keys 返回该选择器引用到的所有字段名，供扫描阶段一次性抽取
+func (s *Selector) Keys() []string {
+	if s.Empty() {
+		return nil
+	}
+	keys := make([]string, 0, len(s.requirements))
+	seen := make(map[string]bool)
+	for _, r := range s.requirements {
+		if !seen[r.Key] {
+			seen[r.Key] = true
+			keys = append(keys, r.Key)
+		}
+	}
+	return keys
+}
+
+// Empty 表示选择器未设置任何条件，即不过滤
+func (s *Selector) Empty() bool {
+	return s == nil || len(s.requirements) == 0
+}
+
+// Matches 判断fields（key->字符串值，字段不存在时不应出现在map中）是否满足全部条件
+func (s *Selector) Matches(fields map[string]string) bool {
+	if s.Empty() {
+		return true
+	}
+
+	for _, r := range s.requirements {
+		val, exists := fields[r.Key]
+
+		switch r.Op {
+		case OpExists:
+			if !exists {
+				return false
+			}
+		case OpNotExists:
+			if exists {
+				return false
+			}
+		case OpEquals:
+			if !exists || val != r.Values[0] {
+				return false
+			}
+		case OpNotEquals:
+			if exists && val == r.Values[0] {
+				return false
+			}
+		case OpIn:
+			if !exists || !containsString(r.Values, val) {
+				return false
+			}
+		case OpNotIn:
+			if exists && containsString(r.Values, val) {
+				return false
+			}
+		case OpGreater, OpLess:
+			if !exists {
+				return false
+			}
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return false
+			}
+			if r.Op == OpGreater && !(n > r.Number) {
+				return false
+			}
+			if r.Op == OpLess && !(n < r.Number) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func TestParseSelectorEmpty(t *testing.T) {
+	sel, err := ParseSelector("")
+	if err != nil {
+		t.Fatalf("ParseSelector(\"\") error: %v", err)
+	}
+	if !sel.Empty() {
+		t.Fatalf("Empty() = false, want true for an empty expression")
+	}
+	if !sel.Matches(map[string]string{"label": "cat"}) {
+		t.Fatalf("Matches() = false, want true when the selector has no requirements")
+	}
+}
+
+func TestParseSelectorAndMatches(t *testing.T) {
+	sel, err := ParseSelector("label in (cat,dog),source!=web,score>0.5,!deleted")
+	if err != nil {
+		t.Fatalf("ParseSelector error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   bool
+	}{
+		{
+			name:   "matches all requirements",
+			fields: map[string]string{"label": "cat", "source": "app", "score": "0.9"},
+			want:   true,
+		},
+		{
+			name:   "label not in the allowed set",
+			fields: map[string]string{"label": "bird", "source": "app", "score": "0.9"},
+			want:   false,
+		},
+		{
+			name:   "source equals the excluded value",
+			fields: map[string]string{"label": "cat", "source": "web", "score": "0.9"},
+			want:   false,
+		},
+		{
+			name:   "score not greater than threshold",
+			fields: map[string]string{"label": "cat", "source": "app", "score": "0.1"},
+			want:   false,
+		},
+		{
+			name:   "deleted key present",
+			fields: map[string]string{"label": "cat", "source": "app", "score": "0.9", "deleted": "true"},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sel.Matches(tt.fields); got != tt.want {
+				t.Errorf("Matches(%v) = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorNotIn(t *testing.T) {
+	sel, err := ParseSelector("label notin (cat,dog)")
+	if err != nil {
+		t.Fatalf("ParseSelector error: %v", err)
+	}
+	if sel.Matches(map[string]string{"label": "cat"}) {
+		t.Fatalf("Matches() = true, want false for a label in the exclusion set")
+	}
+	if !sel.Matches(map[string]string{"label": "bird"}) {
+		t.Fatalf("Matches() = false, want true for a label outside the exclusion set")
+	}
+}
+
+func TestParseSelectorRejectsMalformedInClause(t *testing.T) {
+	cases := []string{
+		"label in(cat,dog)",
+		"label notin(cat,dog)",
+		"label in (cat,dog",
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseSelector(expr); err == nil {
+				t.Fatalf("ParseSelector(%q) error = nil, want a parse error instead of silently falling back to an exists check", expr)
+			}
+		})
+	}
+}
+
+func TestParseSelectorUnbalancedParens(t *testing.T) {
+	if _, err := ParseSelector("label in (cat,dog))"); err == nil {
+		t.Fatalf("ParseSelector with unbalanced parens should return an error")
+	}
+}
+
+func TestSelectorKeys(t *testing.T) {
+	sel, err := ParseSelector("label in (cat,dog),source!=web,label==cat")
+	if err != nil {
+		t.Fatalf("ParseSelector error: %v", err)
+	}
+
+	keys := sel.Keys()
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if seen[k] {
+			t.Fatalf("Keys() contains duplicate key %q", k)
+		}
+		seen[k] = true
+	}
+	if !seen["label"] || !seen["source"] {
+		t.Fatalf("Keys() = %v, want it to contain label and source", keys)
+	}
+}
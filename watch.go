@@ -0,0 +1,539 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/xiaolonghuster/file_stat_example/jsonl"
+)
+
+/*
+# @Desc: 借鉴 `kubectl get -w` 的思路，-watch模式下countLabels完成首次全量扫描后
+# 不退出，而是用fsnotify监听输入目录，对新增/被追加的.jsonl文件做增量扫描，
+# 并把每个文件的(inode, size, offset, mtime)记录进outputFile同目录下的
+# checkpoint sidecar文件，使得任务中断重启后只需处理新增/追加的数据。
+*/
+
+// FileCheckpoint 记录单个文件在上次增量扫描时的位置
+type FileCheckpoint struct {
+	Inode   uint64 `json:"inode"`
+	Size    int64  `json:"size"`
+	Offset  int64  `json:"offset"`
+	ModTime int64  `json:"mtime"` // unix秒
+	Charset string `json:"charset,omitempty"`
+}
+
+// CheckpointStore 持久化维护目录下所有文件的FileCheckpoint，以及目前为止累计的
+// label计数。后者同样需要落盘——否则watch任务重启时，已经跳过"未变化"文件的
+// scanFile不会重新产生任何计数，累计结果就会从零开始，把之前的统计结果冲掉。
+type CheckpointStore struct {
+	mu            sync.Mutex
+	path          string
+	files         map[string]FileCheckpoint
+	counts        map[string]int64
+	totalLines    int64
+	filteredLines int64
+}
+
+// checkpointDocument 是checkpoint sidecar文件落盘时的JSON结构
+type checkpointDocument struct {
+	Files         map[string]FileCheckpoint `json:"files"`
+	LabelCounts   map[string]int64          `json:"label_counts"`
+	TotalLines    int64                     `json:"total_lines"`
+	FilteredLines int64                     `json:"filtered_lines"`
+}
+
+// watchSettlePollInterval 是watch模式下定期重新核对已知文件的轮询间隔，用来在
+// fsnotify之外制造出额外的观测点，让那些此后不会再被继续追加的普通文件的
+// 最后一行（没有换行符结尾）最终也能被flush出去，而不会因为再没有写事件而被
+// 永久搁置。
+//
+// 同时它也是判定"可以放心flush未写完结尾"所要求的最短安静期：只有当文件的
+// mtime比当前时间早至少这么久，才认为上次停在最后一个换行符之后的那段内容
+// 已经写完——而不是简单地比较"这次观测到的size/mtime和上一次观测是否相同"。
+// 后者曾经是个真实的bug：scanFile每次被调用都会把checkpoint里的
+// size/mtime刷新成当前值，哪怕这次什么都没消费（正在等一行写完），于是只要
+// 两次事件之间恰好没有新写入——哪怕间隔只有几十毫秒——就会被误判成"已经连续
+// 两次观测不变"，把还在被追加的半行数据提前flush掉，完全没有起到"等写完"
+// 的保护作用。改成基于墙钟时间的安静期之后，不管事件投递得多频繁，只要文件
+// 最近watchSettlePollInterval之内真的发生过写入，mtime就会被刷新，安静期
+// 判断就不会提前触发。
+const watchSettlePollInterval = 2 * time.Second
+
+// checkpointSidecarPath 返回outputFile对应的checkpoint sidecar文件路径
+func checkpointSidecarPath(outputFile string) string {
+	return outputFile + ".checkpoint.json"
+}
+
+// loadCheckpointStore 加载outputFile对应的sidecar文件，不存在时返回空store
+func loadCheckpointStore(outputFile string) (*CheckpointStore, error) {
+	path := checkpointSidecarPath(outputFile)
+	store := &CheckpointStore{path: path, files: make(map[string]FileCheckpoint), counts: make(map[string]int64)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取checkpoint文件失败[%s]: %v", path, err)
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+
+	var doc checkpointDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析checkpoint文件失败[%s]: %v", path, err)
+	}
+	if doc.Files != nil {
+		store.files = doc.Files
+	}
+	if doc.LabelCounts != nil {
+		store.counts = doc.LabelCounts
+	}
+	store.totalLines = doc.TotalLines
+	store.filteredLines = doc.FilteredLines
+
+	return store, nil
+}
+
+// Get 返回filePath对应的checkpoint，ok为false表示此前从未记录过
+func (s *CheckpointStore) Get(filePath string) (FileCheckpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.files[filePath]
+	return cp, ok
+}
+
+// Set 更新filePath对应的checkpoint，不落盘，落盘需显式调用Save
+func (s *CheckpointStore) Set(filePath string, cp FileCheckpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[filePath] = cp
+}
+
+// FileCount 返回目前记录在案的文件数量，用于进程重启后恢复FilesProcessed
+func (s *CheckpointStore) FileCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// Counts 返回累计label计数的一份拷贝，以及累计的总行数/过滤后行数，
+// 供runWatch在启动时恢复上一次的统计结果，而不是从空map重新开始。
+func (s *CheckpointStore) Counts() (map[string]int64, int64, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int64, len(s.counts))
+	for k, v := range s.counts {
+		counts[k] = v
+	}
+	return counts, s.totalLines, s.filteredLines
+}
+
+// SetCounts 更新累计label计数，不落盘，落盘需显式调用Save
+func (s *CheckpointStore) SetCounts(counts map[string]int64, totalLines, filteredLines int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = counts
+	s.totalLines = totalLines
+	s.filteredLines = filteredLines
+}
+
+// Save 把当前checkpoint集合（包括累计的label计数）原子地写入sidecar文件
+func (s *CheckpointStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := checkpointDocument{
+		Files:         s.files,
+		LabelCounts:   s.counts,
+		TotalLines:    s.totalLines,
+		FilteredLines: s.filteredLines,
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化checkpoint失败: %v", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入checkpoint临时文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("替换checkpoint文件失败: %v", err)
+	}
+	return nil
+}
+
+// fileInode 返回info对应的inode号，用于识别文件是否被删除重建(日志轮转等)而非简单追加
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
+
+// WatchEvent 是watch模式下输出到stdout的增量事件，每行一个JSON对象(NDJSON)，
+// 便于下游管道(如jq)逐行消费。
+type WatchEvent struct {
+	Type      string `json:"type"` // "initial" | "update" | "error"
+	File      string `json:"file,omitempty"`
+	Label     string `json:"label,omitempty"`
+	Delta     int64  `json:"delta,omitempty"`
+	Lines     int64  `json:"lines,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+func emitWatchEvent(ev WatchEvent) {
+	ev.Timestamp = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// processFileFrom 从startOffset处增量读取filePath的剩余内容，返回本次新增的
+// label计数、新扫描/过滤后的行数、下一次应继续读取的offset，以及本次实际
+// 使用的编码名称。正常情况下只消费到最后一个换行符为止——末尾如果有一段
+// 还没写完的内容（没有换行符结尾），会原样留在文件里，等下一次有新写入
+// 触发扫描时再和后续内容拼在一起处理，避免把正在被另一进程追加、尚未写完的
+// 半行JSON提前当成一整行解析，导致数据被错误计数或悄悄丢弃。
+// flushTail为true时，连同末尾这段未换行的内容一起消费——调用方只应在确认
+// 该文件的mtime已经早于当前时间至少watchSettlePollInterval（即真正"写完了"
+// 而不是碰巧在两次事件之间）时才传入true，以正确处理普通静态文件最后一行
+// 没有换行符的情况。
+func processFileFrom(filePath, labelKey string, fastParse bool, sel *Selector, charset string, startOffset int64, flushTail bool) (counts map[string]int64, linesRead, filteredLines, newOffset int64, detectedCharset string, err error) {
+	counts = make(map[string]int64)
+	newOffset = startOffset
+	detectedCharset = charset
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, 0, startOffset, charset, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(startOffset, io.SeekStart); err != nil {
+		return nil, 0, 0, startOffset, charset, fmt.Errorf("定位增量读取位置失败[%s]: %v", filePath, err)
+	}
+
+	raw, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, 0, 0, startOffset, charset, fmt.Errorf("读取增量内容失败[%s]: %v", filePath, err)
+	}
+	if len(raw) == 0 {
+		return counts, 0, 0, startOffset, charset, nil
+	}
+
+	if strings.EqualFold(charset, "auto") {
+		// 只有首次扫描该文件时charset才会是"auto"：按BOM嗅探一次，
+		// 探测结果之后会被写入checkpoint，后续增量调用直接复用。
+		detected, bomLen := detectBOM(raw)
+		detectedCharset = detected
+		raw = raw[bomLen:]
+		startOffset += int64(bomLen)
+	}
+
+	if !flushTail {
+		lastNL := bytes.LastIndexByte(raw, '\n')
+		if lastNL < 0 {
+			// 这次读到的内容里一行都还没写完，原样等待下一次事件
+			return counts, 0, 0, startOffset, detectedCharset, nil
+		}
+		raw = raw[:lastNL+1]
+	}
+
+	newOffset = startOffset + int64(len(raw))
+
+	decoded, err := decodeChunk(raw, detectedCharset)
+	if err != nil {
+		return nil, 0, 0, startOffset, detectedCharset, fmt.Errorf("解码增量内容失败[%s]: %v", filePath, err)
+	}
+
+	if fastParse {
+		selKeys := make([][]byte, 0, len(sel.Keys())+1)
+		selKeys = append(selKeys, []byte(labelKey))
+		for _, k := range sel.Keys() {
+			selKeys = append(selKeys, []byte(k))
+		}
+
+		scanner := jsonl.NewScanner(bytes.NewReader(decoded))
+		scanner.SetMaxLineSize(10 * 1024 * 1024)
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			values, types, err := scanner.ExtractFields(selKeys)
+			if err != nil {
+				continue
+			}
+
+			label := stringifyJSONLValue(values[labelKey], types[labelKey])
+			if label == "" {
+				continue
+			}
+			linesRead++
+
+			if !sel.Matches(jsonlValuesToStrings(values, types)) {
+				continue
+			}
+
+			counts[label]++
+			filteredLines++
+		}
+
+		if err := scanner.Err(); err != nil {
+			return counts, linesRead, filteredLines, newOffset, detectedCharset, fmt.Errorf("扫描增量内容失败[%s]: %v", filePath, err)
+		}
+
+		return counts, linesRead, filteredLines, newOffset, detectedCharset, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(decoded))
+	buf := make([]byte, 0, 256*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		var label string
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &data); err != nil {
+			continue
+		}
+
+		if labelVal, ok := data[labelKey]; ok {
+			label = stringifyJSONValue(labelVal)
+		}
+
+		if label == "" {
+			continue
+		}
+		linesRead++
+
+		if !sel.Matches(stringifyJSONFields(data)) {
+			continue
+		}
+
+		counts[label]++
+		filteredLines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return counts, linesRead, filteredLines, newOffset, detectedCharset, fmt.Errorf("扫描增量内容失败[%s]: %v", filePath, err)
+	}
+
+	return counts, linesRead, filteredLines, newOffset, detectedCharset, nil
+}
+
+// runWatch 完成一次首次全量扫描后不退出，改用fsnotify监听directory，
+// 对新增/被追加的.jsonl文件做增量扫描并持续合并进全局LabelCounts，
+// 每次变化都会把该label的增量以WatchEvent形式打印到stdout，
+// 同时把最新的汇总结果写回outputFile，支持长时间运行的增量统计管道。
+func runWatch(config Config, directory, outputFile string) error {
+	sel, err := ParseSelector(config.Selector)
+	if err != nil {
+		return fmt.Errorf("解析选择器失败: %v", err)
+	}
+
+	store, err := loadCheckpointStore(outputFile)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	// 从上一次运行的checkpoint里恢复累计的label计数，而不是从空map重新开始——
+	// 否则重启后对"文件未变化"的正常跳过会让flushResult把之前的结果清空。
+	totalCounts, totalLines, filteredLines := store.Counts()
+	fileCount := store.FileCount()
+
+	scanFile := func(filePath string) error {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return fmt.Errorf("无法访问文件[%s]: %v", filePath, err)
+		}
+
+		inode := fileInode(info)
+		cp, known := store.Get(filePath)
+		if known && inode != 0 && cp.Inode != 0 && cp.Inode != inode {
+			// 文件被删除重建（例如日志轮转），从头开始重新扫描
+			cp = FileCheckpoint{}
+			known = false
+		}
+
+		charset := config.Charset
+		startOffset := int64(0)
+		flushTail := false
+		if known {
+			startOffset = cp.Offset
+			if cp.Charset != "" {
+				charset = cp.Charset
+			}
+			if startOffset >= info.Size() {
+				return nil // 没有新内容，也没有尚未确认写完的结尾，跳过
+			}
+			if time.Since(info.ModTime()) >= watchSettlePollInterval {
+				// 文件至少有一个安静期没有被写入过了，说明上次处理时停在
+				// 最后一个换行符之后、未写完的那段内容不会再被追加了，
+				// 可以放心当作已经写完的一行来处理。
+				flushTail = true
+			}
+		}
+
+		counts, lines, filtered, newOffset, detected, err := processFileFrom(filePath, config.LabelKey, config.UseFastParse, sel, charset, startOffset, flushTail)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		if !known {
+			fileCount++
+		}
+		for label, c := range counts {
+			totalCounts[label] += c
+			emitWatchEvent(WatchEvent{Type: "update", File: filePath, Label: label, Delta: c})
+		}
+		totalLines += lines
+		filteredLines += filtered
+
+		countsSnapshot := make(map[string]int64, len(totalCounts))
+		for label, c := range totalCounts {
+			countsSnapshot[label] = c
+		}
+		linesSnapshot, filteredSnapshot := totalLines, filteredLines
+		mu.Unlock()
+
+		store.Set(filePath, FileCheckpoint{
+			Inode:   inode,
+			Size:    info.Size(),
+			Offset:  newOffset,
+			ModTime: info.ModTime().Unix(),
+			Charset: detected,
+		})
+		store.SetCounts(countsSnapshot, linesSnapshot, filteredSnapshot)
+		return store.Save()
+	}
+
+	flushResult := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		result := &Result{
+			Directory:      directory,
+			FilesProcessed: fileCount,
+			TotalLines:     totalLines,
+			FilteredLines:  filteredLines,
+			Selector:       config.Selector,
+			UniqueLabels:   len(totalCounts),
+			LabelCounts:    totalCounts,
+		}
+
+		sortedLabels := make([]LabelCount, 0, len(totalCounts))
+		for label, count := range totalCounts {
+			sortedLabels = append(sortedLabels, LabelCount{Label: label, Count: count})
+		}
+		sort.Slice(sortedLabels, func(i, j int) bool {
+			if sortedLabels[i].Count == sortedLabels[j].Count {
+				return sortedLabels[i].Label < sortedLabels[j].Label
+			}
+			return sortedLabels[i].Count > sortedLabels[j].Count
+		})
+		result.SortedLabels = sortedLabels
+
+		if err := saveResult(result, outputFile, config.MaxOutputSize); err != nil {
+			emitWatchEvent(WatchEvent{Type: "error", Error: fmt.Sprintf("保存结果失败: %v", err)})
+		}
+	}
+
+	files, err := collectJSONLFiles(directory)
+	if err != nil {
+		return fmt.Errorf("收集文件失败: %v", err)
+	}
+	for _, f := range files {
+		if err := scanFile(f); err != nil {
+			emitWatchEvent(WatchEvent{Type: "error", File: f, Error: err.Error()})
+		}
+	}
+	flushResult()
+	emitWatchEvent(WatchEvent{Type: "initial", Lines: totalLines})
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监听器失败: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(directory); err != nil {
+		return fmt.Errorf("监听目录失败[%s]: %v", directory, err)
+	}
+
+	fmt.Printf("已进入watch模式，持续监听目录: %s (Ctrl+C退出)\n", directory)
+
+	settleTicker := time.NewTicker(watchSettlePollInterval)
+	defer settleTicker.Stop()
+
+	for {
+		select {
+		case <-settleTicker.C:
+			// 定期重新核对一遍已知文件，让mtime安静期的判断在没有新写事件时
+			// 也有机会执行，从而flush掉那些已经真正写完的未换行结尾。
+			files, err := collectJSONLFiles(directory)
+			if err != nil {
+				emitWatchEvent(WatchEvent{Type: "error", Error: fmt.Sprintf("定期核对目录失败: %v", err)})
+				continue
+			}
+			for _, f := range files {
+				if err := scanFile(f); err != nil {
+					emitWatchEvent(WatchEvent{Type: "error", File: f, Error: err.Error()})
+				}
+			}
+			flushResult()
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			ext := strings.ToLower(filepath.Ext(event.Name))
+			if ext != ".jsonl" && ext != ".json" {
+				continue
+			}
+			if err := scanFile(event.Name); err != nil {
+				emitWatchEvent(WatchEvent{Type: "error", File: event.Name, Error: err.Error()})
+				continue
+			}
+			flushResult()
+
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			emitWatchEvent(WatchEvent{Type: "error", Error: watchErr.Error()})
+		}
+	}
+}